@@ -41,14 +41,84 @@ type Config struct {
 	// Tags to categorize the execution
 	Tags []string `json:"tags"`
 
-	// Configurable parameters for the execution
+	// Configurable parameters for the execution, which nodes can read through
+	// ConfigFromContext, e.g. a model name or user ID
 	Configurable map[string]interface{} `json:"configurable"`
 
-	// RunName for this execution
+	// RunName for this execution, reported to callbacks and tracers in place of "graph"
 	RunName string `json:"run_name"`
 
-	// Timeout for the execution
+	// Timeout for the execution, after which the context of its nodes is cancelled
+	// and the run fails with context.DeadlineExceeded
 	Timeout *time.Duration `json:"timeout"`
+
+	// InterruptBefore pauses this execution before the given nodes, in addition to
+	// the breakpoints set when the graph was compiled
+	InterruptBefore []string `json:"interrupt_before"`
+
+	// InterruptAfter pauses this execution after the given nodes, in addition to
+	// the breakpoints set when the graph was compiled
+	InterruptAfter []string `json:"interrupt_after"`
+
+	// ResumeFrom starts this execution at the given nodes instead of the entry point.
+	// The input is then taken as the complete state, e.g. one loaded from a checkpoint.
+	ResumeFrom []string `json:"resume_from"`
+
+	// MaxConcurrency bounds how many nodes of a step run at once, including the nodes
+	// reached through a Send. Zero runs every node of a step at once.
+	MaxConcurrency int `json:"max_concurrency"`
+
+	// RecursionLimit is the number of supersteps this execution may take before it fails
+	// with ErrRecursionLimit. Zero uses DefaultRecursionLimit.
+	RecursionLimit int `json:"recursion_limit"`
+}
+
+// maxConcurrency returns the concurrency bound of the config, which may be nil
+func (c *Config) maxConcurrency() int {
+	if c == nil {
+		return 0
+	}
+	return c.MaxConcurrency
+}
+
+// runName returns the name the run reports to callbacks and tracers
+func (c *Config) runName() string {
+	if c == nil || c.RunName == "" {
+		return "graph"
+	}
+	return c.RunName
+}
+
+type configKey struct{}
+
+// withConfig applies the config of an invocation to its context: nodes can read the config
+// with ConfigFromContext, and the context is cancelled once the Timeout elapses. A nil
+// config keeps the config of the enclosing run, e.g. for a subgraph.
+func withConfig(ctx context.Context, config *Config) (context.Context, context.CancelFunc) {
+	if config == nil {
+		return ctx, func() {}
+	}
+
+	ctx = context.WithValue(ctx, configKey{}, config)
+	if config.Timeout != nil && *config.Timeout > 0 {
+		return context.WithTimeout(ctx, *config.Timeout)
+	}
+	return ctx, func() {}
+}
+
+// ConfigFromContext returns the config of the run executing the calling node, e.g. to read
+// per-run parameters from Configurable. It returns nil when the run has no config.
+func ConfigFromContext(ctx context.Context) *Config {
+	config, _ := ctx.Value(configKey{}).(*Config)
+	return config
+}
+
+// recursionLimit returns the recursion limit of the config, which may be nil
+func (c *Config) recursionLimit() int {
+	if c == nil || c.RecursionLimit <= 0 {
+		return DefaultRecursionLimit
+	}
+	return c.RecursionLimit
 }
 
 // NoOpCallbackHandler provides a no-op implementation of CallbackHandler
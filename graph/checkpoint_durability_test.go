@@ -0,0 +1,151 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+// flakyStore is a memory store whose saves fail once failAfter checkpoints were saved
+type flakyStore struct {
+	*graph.MemoryCheckpointStore
+
+	failAfter int64
+	saves     atomic.Int64
+}
+
+func (s *flakyStore) Save(ctx context.Context, checkpoint *graph.Checkpoint) error {
+	if s.failAfter >= 0 && s.saves.Load() >= s.failAfter {
+		return errors.New("disk full")
+	}
+	s.saves.Add(1)
+	return s.MemoryCheckpointStore.Save(ctx, checkpoint)
+}
+
+func TestCheckpointableRunnable_Durability(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		durability graph.Durability
+		// savesSeen is the number of checkpoints saved when the last node starts
+		savesSeen int64
+	}{
+		{name: "sync", durability: graph.DurabilitySync, savesSeen: 2},
+		{name: "exit", durability: graph.DurabilityExit, savesSeen: 0},
+		{name: "async", durability: graph.DurabilityAsync, savesSeen: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := &flakyStore{MemoryCheckpointStore: graph.NewMemoryCheckpointStore(), failAfter: -1}
+			g := newCounterGraph(2, graph.CheckpointConfig{Store: store, AutoSave: true, Durability: tt.durability})
+
+			var seen int64
+			g.AddNode("last", func(_ context.Context, state interface{}) (interface{}, error) {
+				seen = store.saves.Load()
+				return state, nil
+			})
+			g.AddEdge("step_1", "last")
+			g.AddEdge("last", graph.END)
+
+			runnable, err := g.CompileCheckpointable()
+			if err != nil {
+				t.Fatalf("failed to compile: %v", err)
+			}
+
+			if _, err := runnable.InvokeWithConfig(context.Background(), 0, threadConfig("durable")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.savesSeen >= 0 && seen != tt.savesSeen {
+				t.Errorf("expected %d saved checkpoints when the last node ran, got %d", tt.savesSeen, seen)
+			}
+			// Every checkpoint is written by the time the run returns
+			if saves := store.saves.Load(); saves != 3 {
+				t.Errorf("expected 3 saved checkpoints, got %d", saves)
+			}
+		})
+	}
+}
+
+func TestCheckpointableRunnable_SaveErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, durability := range []graph.Durability{graph.DurabilitySync, graph.DurabilityAsync, graph.DurabilityExit} {
+		store := &flakyStore{MemoryCheckpointStore: graph.NewMemoryCheckpointStore(), failAfter: 1}
+		runnable, err := newCounterGraph(3, graph.CheckpointConfig{
+			Store:      store,
+			AutoSave:   true,
+			Durability: durability,
+		}).CompileCheckpointable()
+		if err != nil {
+			t.Fatalf("failed to compile: %v", err)
+		}
+
+		_, err = runnable.Invoke(context.Background(), 0)
+		if err == nil || !strings.Contains(err.Error(), "failed to save checkpoint: disk full") {
+			t.Errorf("durability %d: expected the failed save to fail the run, got %v", durability, err)
+		}
+	}
+
+	// A failed save stops a synchronous run before the next node
+	store := &flakyStore{MemoryCheckpointStore: graph.NewMemoryCheckpointStore(), failAfter: 0}
+	g := newCounterGraph(1, graph.CheckpointConfig{Store: store, AutoSave: true, Durability: graph.DurabilitySync})
+	ran := false
+	g.AddNode("after", func(_ context.Context, state interface{}) (interface{}, error) {
+		ran = true
+		return state, nil
+	})
+	g.AddEdge("step_0", "after")
+	g.AddEdge("after", graph.END)
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+	if _, err := runnable.Invoke(context.Background(), 0); err == nil || !strings.Contains(err.Error(), "error in node step_0") {
+		t.Errorf("expected the run to fail in step_0, got %v", err)
+	}
+	if ran {
+		t.Error("expected the node after the failed save not to run")
+	}
+}
+
+func TestCheckpointableRunnable_OnSaveError(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mutex  sync.Mutex
+		failed []string
+	)
+	store := &flakyStore{MemoryCheckpointStore: graph.NewMemoryCheckpointStore(), failAfter: 1}
+	runnable, err := newCounterGraph(3, graph.CheckpointConfig{
+		Store:      store,
+		AutoSave:   true,
+		Durability: graph.DurabilitySync,
+		OnSaveError: func(_ context.Context, checkpoint *graph.Checkpoint, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			failed = append(failed, checkpoint.NodeName+": "+err.Error())
+		},
+	}).CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), 0)
+	if err != nil || result != 3 {
+		t.Fatalf("expected the run to succeed with 3, got %v (%v)", result, err)
+	}
+	if len(failed) != 2 || failed[0] != "step_1: disk full" {
+		t.Errorf("expected the handler to see two failed saves, got %v", failed)
+	}
+}
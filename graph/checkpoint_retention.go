@@ -0,0 +1,65 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+)
+
+// prune deletes the checkpoints of a thread that no retention rule keeps. Through their
+// ParentID the checkpoints of a thread form a tree, which branches when UpdateState forks
+// a past checkpoint. The MaxCheckpoints latest checkpoints along the lineage of every branch
+// are kept, as are the first checkpoint with KeepFirst and every KeepEvery-th version.
+// Later checkpoints keep their ParentID even when their parent is deleted.
+func (cr *CheckpointableRunnable) prune(ctx context.Context, threadID string) error {
+	if cr.config.MaxCheckpoints <= 0 {
+		return nil
+	}
+
+	checkpoints, err := cr.history(ctx, threadID)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]*Checkpoint, len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		byID[checkpoint.ID] = checkpoint
+	}
+	hasChild := make(map[string]bool, len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		if _, ok := byID[checkpoint.ParentID]; ok {
+			hasChild[checkpoint.ParentID] = true
+		}
+	}
+
+	// Every checkpoint without children ends a branch, whose lineage is walked up to its
+	// root or to a checkpoint whose parent was already pruned
+	keep := make(map[string]bool, len(checkpoints))
+	for _, leaf := range checkpoints {
+		if hasChild[leaf.ID] {
+			continue
+		}
+		checkpoint := leaf
+		for n := 0; checkpoint != nil && n < cr.config.MaxCheckpoints; n++ {
+			keep[checkpoint.ID] = true
+			checkpoint = byID[checkpoint.ParentID]
+		}
+	}
+
+	for _, checkpoint := range checkpoints {
+		if keep[checkpoint.ID] {
+			continue
+		}
+		if cr.config.KeepFirst && checkpoint.ParentID == "" {
+			continue
+		}
+		if cr.config.KeepEvery > 0 && checkpoint.Version%cr.config.KeepEvery == 0 {
+			continue
+		}
+
+		if err := cr.config.Store.Delete(ctx, checkpoint.ID); err != nil {
+			return fmt.Errorf("failed to prune checkpoint %s: %w", checkpoint.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package graph_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+// newCounterGraph returns a checkpointable chain of nodes that each add one to an int state
+func newCounterGraph(nodes int, config graph.CheckpointConfig) *graph.CheckpointableMessageGraph {
+	g := graph.NewCheckpointableMessageGraphWithConfig(config)
+	for i := 0; i < nodes; i++ {
+		name := fmt.Sprintf("step_%d", i)
+		g.AddNode(name, func(_ context.Context, state interface{}) (interface{}, error) {
+			return state.(int) + 1, nil
+		})
+		if i > 0 {
+			g.AddEdge(fmt.Sprintf("step_%d", i-1), name)
+		}
+	}
+	g.AddEdge(fmt.Sprintf("step_%d", nodes-1), graph.END)
+	g.SetEntryPoint("step_0")
+	return g
+}
+
+func TestCheckpointableRunnable_MaxCheckpoints(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		keepFirst bool
+		keepEvery int
+		versions  []int
+	}{
+		{name: "latest only", versions: []int{10, 9, 8}},
+		{name: "keep first", keepFirst: true, versions: []int{10, 9, 8, 1}},
+		{name: "keep every 4th", keepEvery: 4, versions: []int{10, 9, 8, 4}},
+		{name: "keep first and every 3rd", keepFirst: true, keepEvery: 3, versions: []int{10, 9, 8, 6, 3, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			runnable, err := newCounterGraph(10, graph.CheckpointConfig{
+				Store:          graph.NewMemoryCheckpointStore(),
+				AutoSave:       true,
+				MaxCheckpoints: 3,
+				KeepFirst:      tt.keepFirst,
+				KeepEvery:      tt.keepEvery,
+			}).CompileCheckpointable()
+			if err != nil {
+				t.Fatalf("failed to compile: %v", err)
+			}
+
+			ctx := context.Background()
+			if result, err := runnable.InvokeWithConfig(ctx, 0, threadConfig("pruned")); err != nil || result != 10 {
+				t.Fatalf("expected 10, got %v (%v)", result, err)
+			}
+
+			history, err := runnable.GetStateHistory(ctx, "pruned")
+			if err != nil {
+				t.Fatalf("failed to get history: %v", err)
+			}
+
+			versions := make([]int, len(history))
+			for i, snapshot := range history {
+				versions[i] = snapshot.Version
+			}
+			if !reflect.DeepEqual(versions, tt.versions) {
+				t.Errorf("expected versions %v, got %v", tt.versions, versions)
+			}
+		})
+	}
+}
+
+func TestCheckpointableRunnable_MaxCheckpointsFork(t *testing.T) {
+	t.Parallel()
+
+	runnable, err := newCounterGraph(5, graph.CheckpointConfig{
+		Store:          graph.NewMemoryCheckpointStore(),
+		AutoSave:       true,
+		MaxCheckpoints: 3,
+	}).CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	if result, err := runnable.InvokeWithConfig(ctx, 0, threadConfig("forked")); err != nil || result != 5 {
+		t.Fatalf("expected 5, got %v (%v)", result, err)
+	}
+
+	history, err := runnable.GetStateHistory(ctx, "forked")
+	if err != nil || len(history) != 3 {
+		t.Fatalf("expected 3 checkpoints, got %d (%v)", len(history), err)
+	}
+
+	// Fork the oldest remaining checkpoint, at version 3, and run the new branch to the end
+	if _, err := runnable.UpdateState(ctx, "forked", history[2].CheckpointID, 100, ""); err != nil {
+		t.Fatalf("failed to update state: %v", err)
+	}
+	if result, err := runnable.InvokeWithConfig(ctx, nil, threadConfig("forked")); err != nil || result != 102 {
+		t.Fatalf("expected 102, got %v (%v)", result, err)
+	}
+
+	history, err = runnable.GetStateHistory(ctx, "forked")
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+
+	// Each branch keeps its latest checkpoints, and the fork point is shared by both
+	versions := make([]int, len(history))
+	for i, snapshot := range history {
+		versions[i] = snapshot.Version
+	}
+	if expected := []int{8, 7, 6, 5, 4, 3}; !reflect.DeepEqual(versions, expected) {
+		t.Errorf("expected versions %v, got %v", expected, versions)
+	}
+}
+
+func TestCheckpointableRunnable_SaveModes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		config   graph.CheckpointConfig
+		expected []string
+	}{
+		{
+			name:     "every node",
+			config:   graph.CheckpointConfig{SaveMode: graph.SaveModeEveryNode},
+			expected: []string{"step_2", "step_1", "step_0"},
+		},
+		{
+			name:     "on exit",
+			config:   graph.CheckpointConfig{AutoSave: true, SaveMode: graph.SaveModeOnExit},
+			expected: []string{"step_2"},
+		},
+		{
+			name:     "long interval saves the final state",
+			config:   graph.CheckpointConfig{SaveInterval: time.Hour},
+			expected: []string{"step_2"},
+		},
+		{
+			name:     "short interval",
+			config:   graph.CheckpointConfig{SaveMode: graph.SaveModeInterval, SaveInterval: time.Nanosecond},
+			expected: []string{"step_2", "step_1", "step_0"},
+		},
+		{
+			name:     "no auto save",
+			config:   graph.CheckpointConfig{},
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.config.Store = graph.NewMemoryCheckpointStore()
+			runnable, err := newCounterGraph(3, tt.config).CompileCheckpointable()
+			if err != nil {
+				t.Fatalf("failed to compile: %v", err)
+			}
+
+			ctx := context.Background()
+			if result, err := runnable.InvokeWithConfig(ctx, 0, threadConfig("modes")); err != nil || result != 3 {
+				t.Fatalf("expected 3, got %v (%v)", result, err)
+			}
+
+			history, err := runnable.GetStateHistory(ctx, "modes")
+			if err != nil {
+				t.Fatalf("failed to get history: %v", err)
+			}
+
+			nodes := []string{}
+			for _, snapshot := range history {
+				nodes = append(nodes, snapshot.NodeName)
+			}
+			if !reflect.DeepEqual(nodes, tt.expected) {
+				t.Errorf("expected checkpoints %v, got %v", tt.expected, nodes)
+			}
+			if len(history) > 0 && history[0].Values != 3 {
+				t.Errorf("expected the final state to be saved, got %v", history[0].Values)
+			}
+		})
+	}
+}
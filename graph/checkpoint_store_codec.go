@@ -0,0 +1,279 @@
+package graph
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// codecCheckpointStore is the base of the CheckpointStore decorators that transform serialized
+// states. A state is serialized, encoded by the decorator, and handed to the wrapped store as
+// a string of the form "<scheme>:[<header>:]<base64 payload>", which every store can persist.
+// The states of sends, the values of pending writes and the metadata listed in
+// codecMetadataKeys are encoded the same way, and the checkpoint is marked with the
+// codecMarker of the scheme. Checkpoints without the mark, such as those saved before the
+// decorator was added, are returned as they are, and decorators can be stacked.
+type codecCheckpointStore struct {
+	store      CheckpointStore
+	serializer Serializer
+	scheme     string
+
+	encode func(ctx context.Context, checkpoint *Checkpoint, data []byte) (header string, payload []byte, err error)
+	decode func(ctx context.Context, checkpoint *Checkpoint, header string, payload []byte) ([]byte, error)
+}
+
+// codecMetadataKeys are the checkpoint metadata keys whose values come from the run, such as
+// interrupt values and error messages, and are encoded like states. Other metadata, such as
+// the event and thread ID, is left for the wrapped store to index.
+var codecMetadataKeys = []string{"interrupt_value", "resume_values", "error"}
+
+// codecMarker returns the metadata key that marks the checkpoints whose values were encoded
+// with the given scheme, so that plain values are never taken for encoded ones
+func codecMarker(scheme string) string {
+	return "encoded:" + scheme
+}
+
+// SetSerializer sets the serializer used for states before they are encoded.
+// JSONSerializer is used by default.
+func (s *codecCheckpointStore) SetSerializer(serializer Serializer) {
+	s.serializer = serializer
+}
+
+// Save implements CheckpointStore interface
+func (s *codecCheckpointStore) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	encoded := *checkpoint
+
+	var err error
+	if encoded.State, err = s.encodeValue(ctx, checkpoint, checkpoint.State); err != nil {
+		return err
+	}
+
+	if encoded.Sends, err = s.encodeSends(ctx, checkpoint, checkpoint.Sends); err != nil {
+		return err
+	}
+
+	if encoded.Metadata, err = s.encodeMetadata(ctx, checkpoint); err != nil {
+		return err
+	}
+
+	if len(checkpoint.PendingWrites) > 0 {
+		encoded.PendingWrites = make([]PendingWrite, len(checkpoint.PendingWrites))
+		for i, write := range checkpoint.PendingWrites {
+			encoded.PendingWrites[i] = write
+			if encoded.PendingWrites[i].Value, err = s.encodeValue(ctx, checkpoint, write.Value); err != nil {
+				return err
+			}
+			if encoded.PendingWrites[i].Sends, err = s.encodeSends(ctx, checkpoint, write.Sends); err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.store.Save(ctx, &encoded)
+}
+
+// encodeSends returns the sends with their states encoded
+func (s *codecCheckpointStore) encodeSends(ctx context.Context, checkpoint *Checkpoint, sends []Send) ([]Send, error) {
+	if len(sends) == 0 {
+		return nil, nil
+	}
+
+	encoded := make([]Send, len(sends))
+	for i, send := range sends {
+		encoded[i].Node = send.Node
+
+		var err error
+		if encoded[i].State, err = s.encodeValue(ctx, checkpoint, send.State); err != nil {
+			return nil, err
+		}
+	}
+	return encoded, nil
+}
+
+// encodeMetadata returns a copy of the checkpoint's metadata with the values of
+// codecMetadataKeys encoded and the codec's marker set
+func (s *codecCheckpointStore) encodeMetadata(ctx context.Context, checkpoint *Checkpoint) (map[string]interface{}, error) {
+	encoded := make(map[string]interface{}, len(checkpoint.Metadata)+1)
+	for key, value := range checkpoint.Metadata {
+		encoded[key] = value
+	}
+	encoded[codecMarker(s.scheme)] = true
+	for _, key := range codecMetadataKeys {
+		value, ok := checkpoint.Metadata[key]
+		if !ok {
+			continue
+		}
+
+		var err error
+		if encoded[key], err = s.encodeValue(ctx, checkpoint, value); err != nil {
+			return nil, err
+		}
+	}
+	return encoded, nil
+}
+
+// Load implements CheckpointStore interface
+func (s *codecCheckpointStore) Load(ctx context.Context, checkpointID string) (*Checkpoint, error) {
+	checkpoint, err := s.store.Load(ctx, checkpointID)
+	if err != nil {
+		return nil, err
+	}
+	return s.decodeCheckpoint(ctx, checkpoint)
+}
+
+// List implements CheckpointStore interface
+func (s *codecCheckpointStore) List(ctx context.Context, executionID string) ([]*Checkpoint, error) {
+	checkpoints, err := s.store.List(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := make([]*Checkpoint, len(checkpoints))
+	for i, checkpoint := range checkpoints {
+		if decoded[i], err = s.decodeCheckpoint(ctx, checkpoint); err != nil {
+			return nil, err
+		}
+	}
+	return decoded, nil
+}
+
+// Delete implements CheckpointStore interface
+func (s *codecCheckpointStore) Delete(ctx context.Context, checkpointID string) error {
+	return s.store.Delete(ctx, checkpointID)
+}
+
+// Clear implements CheckpointStore interface
+func (s *codecCheckpointStore) Clear(ctx context.Context, executionID string) error {
+	return s.store.Clear(ctx, executionID)
+}
+
+// encodeValue serializes and encodes a state, sent state, pending write or metadata value of
+// a checkpoint
+func (s *codecCheckpointStore) encodeValue(ctx context.Context, checkpoint *Checkpoint, value interface{}) (string, error) {
+	data, err := defaultSerializer(s.serializer).Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal checkpoint state: %w", err)
+	}
+
+	header, payload, err := s.encode(ctx, checkpoint, data)
+	if err != nil {
+		return "", err
+	}
+
+	prefix := s.scheme + ":"
+	if header != "" {
+		prefix += header + ":"
+	}
+	return prefix + base64.StdEncoding.EncodeToString(payload), nil
+}
+
+// decodeCheckpoint returns a copy of the checkpoint with its states and pending writes
+// decoded. A checkpoint without the codec's marker is returned as it is.
+func (s *codecCheckpointStore) decodeCheckpoint(ctx context.Context, checkpoint *Checkpoint) (*Checkpoint, error) {
+	if _, ok := checkpoint.Metadata[codecMarker(s.scheme)]; !ok {
+		return checkpoint, nil
+	}
+
+	decoded := *checkpoint
+
+	var err error
+	if decoded.State, err = s.decodeValue(ctx, checkpoint, checkpoint.State); err != nil {
+		return nil, err
+	}
+
+	if decoded.Sends, err = s.decodeSends(ctx, checkpoint, checkpoint.Sends); err != nil {
+		return nil, err
+	}
+
+	if decoded.Metadata, err = s.decodeMetadata(ctx, checkpoint); err != nil {
+		return nil, err
+	}
+
+	if len(checkpoint.PendingWrites) > 0 {
+		decoded.PendingWrites = make([]PendingWrite, len(checkpoint.PendingWrites))
+		for i, write := range checkpoint.PendingWrites {
+			decoded.PendingWrites[i] = write
+			if decoded.PendingWrites[i].Value, err = s.decodeValue(ctx, checkpoint, write.Value); err != nil {
+				return nil, err
+			}
+			if decoded.PendingWrites[i].Sends, err = s.decodeSends(ctx, checkpoint, write.Sends); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &decoded, nil
+}
+
+// decodeSends returns the sends with their states decoded
+func (s *codecCheckpointStore) decodeSends(ctx context.Context, checkpoint *Checkpoint, sends []Send) ([]Send, error) {
+	if len(sends) == 0 {
+		return nil, nil
+	}
+
+	decoded := make([]Send, len(sends))
+	for i, send := range sends {
+		decoded[i].Node = send.Node
+
+		var err error
+		if decoded[i].State, err = s.decodeValue(ctx, checkpoint, send.State); err != nil {
+			return nil, err
+		}
+	}
+	return decoded, nil
+}
+
+// decodeMetadata returns a copy of the checkpoint's metadata with the values of
+// codecMetadataKeys decoded and the codec's marker removed
+func (s *codecCheckpointStore) decodeMetadata(ctx context.Context, checkpoint *Checkpoint) (map[string]interface{}, error) {
+	decoded := make(map[string]interface{}, len(checkpoint.Metadata))
+	for key, value := range checkpoint.Metadata {
+		decoded[key] = value
+	}
+	delete(decoded, codecMarker(s.scheme))
+	if len(decoded) == 0 {
+		return nil, nil
+	}
+	for _, key := range codecMetadataKeys {
+		value, ok := checkpoint.Metadata[key]
+		if !ok {
+			continue
+		}
+
+		var err error
+		if decoded[key], err = s.decodeValue(ctx, checkpoint, value); err != nil {
+			return nil, err
+		}
+	}
+	return decoded, nil
+}
+
+// decodeValue decodes a value encoded by encodeValue
+func (s *codecCheckpointStore) decodeValue(ctx context.Context, checkpoint *Checkpoint, value interface{}) (interface{}, error) {
+	encoded, ok := value.(string)
+	if !ok || !strings.HasPrefix(encoded, s.scheme+":") {
+		return nil, fmt.Errorf("failed to decode checkpoint %s: value is not encoded with %s", checkpoint.ID, s.scheme)
+	}
+
+	// The header, if any, ends at the last colon; base64 never contains one
+	rest := strings.TrimPrefix(encoded, s.scheme+":")
+	split := strings.LastIndex(rest, ":") + 1
+	header, body := rest[:split], rest[split:]
+
+	payload, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint %s: %w", checkpoint.ID, err)
+	}
+
+	data, err := s.decode(ctx, checkpoint, strings.TrimSuffix(header, ":"), payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint %s: %w", checkpoint.ID, err)
+	}
+
+	state, err := defaultSerializer(s.serializer).Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checkpoint state: %w", err)
+	}
+	return state, nil
+}
@@ -0,0 +1,256 @@
+package graph_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+func TestCompressedCheckpointStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	for _, compression := range []graph.Compression{graph.CompressionGzip, graph.CompressionZstd} {
+		backend := graph.NewMemoryCheckpointStore()
+		store, err := graph.NewCompressedCheckpointStore(backend, compression)
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+
+		transcript := strings.Repeat("the quick brown fox jumps over the lazy dog ", 200)
+		if err := store.Save(ctx, &graph.Checkpoint{ID: "cp", ThreadID: "t", State: transcript}); err != nil {
+			t.Fatalf("%s: failed to save checkpoint: %v", compression, err)
+		}
+
+		raw, _ := backend.Load(ctx, "cp")
+		encoded, ok := raw.State.(string)
+		if !ok || !strings.HasPrefix(encoded, "compressed:"+string(compression)+":") || len(encoded) >= len(transcript)/4 {
+			t.Errorf("%s: expected a compressed state in the backend, got %d bytes", compression, len(encoded))
+		}
+
+		loaded, err := store.Load(ctx, "cp")
+		if err != nil || loaded.State != transcript {
+			t.Errorf("%s: expected the original state back, got error %v", compression, err)
+		}
+
+		if err := store.Close(); err != nil {
+			t.Errorf("%s: failed to close store: %v", compression, err)
+		}
+	}
+
+	// Checkpoints keep loading after switching algorithms, or when saved before compression
+	backend := graph.NewMemoryCheckpointStore()
+	_ = backend.Save(ctx, &graph.Checkpoint{ID: "plain", ThreadID: "t", State: "plain"})
+	gzipStore, _ := graph.NewCompressedCheckpointStore(backend, graph.CompressionGzip)
+	_ = gzipStore.Save(ctx, &graph.Checkpoint{ID: "gzip", ThreadID: "t", State: "gzip"})
+
+	zstdStore, _ := graph.NewCompressedCheckpointStore(backend, graph.CompressionZstd)
+	listed, err := zstdStore.List(ctx, "t")
+	if err != nil || len(listed) != 2 {
+		t.Fatalf("expected 2 checkpoints, got %d (%v)", len(listed), err)
+	}
+	for _, checkpoint := range listed {
+		if checkpoint.State != checkpoint.ID {
+			t.Errorf("expected state %q, got %v", checkpoint.ID, checkpoint.State)
+		}
+	}
+
+	if _, err := graph.NewCompressedCheckpointStore(backend, "lz4"); err == nil {
+		t.Error("expected error for unsupported compression")
+	}
+}
+
+func TestEncryptedCheckpointStore_KeyRotation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := graph.NewMemoryCheckpointStore()
+	keys := graph.NewKeyRing("2024", bytes.Repeat([]byte{1}, 32))
+	store := graph.NewEncryptedCheckpointStore(backend, keys)
+
+	secret := map[string]interface{}{"email": "jane@example.com"}
+	if err := store.Save(ctx, &graph.Checkpoint{ID: "old", ThreadID: "t", State: secret}); err != nil {
+		t.Fatalf("failed to save checkpoint: %v", err)
+	}
+
+	keys.Rotate("2025", bytes.Repeat([]byte{2}, 32))
+	if err := store.Save(ctx, &graph.Checkpoint{ID: "new", ThreadID: "t", State: secret}); err != nil {
+		t.Fatalf("failed to save checkpoint: %v", err)
+	}
+
+	for id, keyID := range map[string]string{"old": "2024", "new": "2025"} {
+		raw, _ := backend.Load(ctx, id)
+		encoded, _ := raw.State.(string)
+		if !strings.HasPrefix(encoded, "aes-gcm:"+keyID+":") || strings.Contains(encoded, "jane") {
+			t.Errorf("expected %s to be encrypted with key %s, got %q", id, keyID, encoded)
+		}
+
+		loaded, err := store.Load(ctx, id)
+		if err != nil {
+			t.Fatalf("failed to load %s: %v", id, err)
+		}
+		if state, _ := loaded.State.(map[string]interface{}); state["email"] != "jane@example.com" {
+			t.Errorf("expected decrypted state for %s, got %v", id, loaded.State)
+		}
+	}
+
+	// States are bound to their checkpoint
+	raw, _ := backend.Load(ctx, "old")
+	_ = backend.Save(ctx, &graph.Checkpoint{ID: "copy", ThreadID: "t", State: raw.State, Metadata: raw.Metadata})
+	if _, err := store.Load(ctx, "copy"); err == nil {
+		t.Error("expected a state moved to another checkpoint to fail decryption")
+	}
+
+	other := graph.NewEncryptedCheckpointStore(backend, graph.NewKeyRing("2025", bytes.Repeat([]byte{2}, 32)))
+	if _, err := other.Load(ctx, "old"); err == nil || !strings.Contains(err.Error(), "unknown encryption key") {
+		t.Errorf("expected unknown key error, got %v", err)
+	}
+}
+
+func TestCheckpointStoreDecorators_Stacked(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend, err := graph.NewFileCheckpointStoreWithDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	encrypted := graph.NewEncryptedCheckpointStore(backend, graph.NewKeyRing("k", bytes.Repeat([]byte{7}, 16)))
+	store, err := graph.NewCompressedCheckpointStore(encrypted, graph.CompressionZstd)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	store.SetSerializer(graph.NewTaggedJSONSerializer(newTicketRegistry()))
+
+	g := newCounterGraph(2, graph.CheckpointConfig{Store: store, AutoSave: true})
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	if result, err := runnable.InvokeWithConfig(ctx, 0, threadConfig("stacked")); err != nil || result != 2 {
+		t.Fatalf("expected 2, got %v (%v)", result, err)
+	}
+
+	state, err := runnable.GetState(ctx, "stacked")
+	if err != nil || state.Values != 2 {
+		t.Errorf("expected the int state to round-trip, got %v (%v)", state, err)
+	}
+
+	ticket := &graph.Checkpoint{
+		ID:            "ticket",
+		ThreadID:      "stacked",
+		State:         ticketState{Title: "bug"},
+		PendingWrites: []graph.PendingWrite{{TaskID: "triage", Value: ticketState{Title: "bug", Priority: 1}}},
+		Timestamp:     time.Now(),
+	}
+	if err := store.Save(ctx, ticket); err != nil {
+		t.Fatalf("failed to save checkpoint: %v", err)
+	}
+	loaded, err := store.Load(ctx, "ticket")
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+	if ticket, ok := loaded.State.(ticketState); !ok || ticket.Title != "bug" {
+		t.Errorf("expected ticketState, got %#v", loaded.State)
+	}
+	if len(loaded.PendingWrites) != 1 {
+		t.Fatalf("expected 1 pending write, got %d", len(loaded.PendingWrites))
+	}
+	if write, ok := loaded.PendingWrites[0].Value.(ticketState); !ok || write.Priority != 1 {
+		t.Errorf("expected the pending write to round-trip, got %#v", loaded.PendingWrites[0].Value)
+	}
+}
+
+func TestEncryptedCheckpointStore_Metadata(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := graph.NewMemoryCheckpointStore()
+	store := graph.NewEncryptedCheckpointStore(backend, graph.NewKeyRing("k", bytes.Repeat([]byte{3}, 32)))
+
+	g := graph.NewCheckpointableMessageGraphWithConfig(graph.CheckpointConfig{Store: store, AutoSave: true})
+	g.AddNode("verify", func(ctx context.Context, state interface{}) (interface{}, error) {
+		ssn, err := graph.Interrupt(ctx, fmt.Sprintf("SSN of %s?", state))
+		if err != nil {
+			return nil, err
+		}
+		dob, err := graph.Interrupt(ctx, "date of birth?")
+		if err != nil {
+			return nil, err
+		}
+		return fmt.Sprintf("%s %s %s", state, ssn, dob), nil
+	})
+	g.AddEdge("verify", graph.END)
+	g.SetEntryPoint("verify")
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	if _, err := runnable.InvokeWithConfig(ctx, "jane", threadConfig("kyc")); !errors.Is(err, graph.ErrGraphInterrupted) {
+		t.Fatalf("expected an interrupt, got %v", err)
+	}
+	if _, err := runnable.InvokeWithConfig(ctx, graph.Resume("123-45-6789"), threadConfig("kyc")); !errors.Is(err, graph.ErrGraphInterrupted) {
+		t.Fatalf("expected a second interrupt, got %v", err)
+	}
+
+	// Interrupt and resume values never reach the backend in the clear
+	raw, err := backend.List(ctx, "kyc")
+	if err != nil {
+		t.Fatalf("failed to list checkpoints: %v", err)
+	}
+	for _, checkpoint := range raw {
+		if metadata := fmt.Sprint(checkpoint.Metadata); strings.Contains(metadata, "jane") || strings.Contains(metadata, "6789") {
+			t.Errorf("expected encrypted metadata, got %s", metadata)
+		}
+	}
+
+	state, err := runnable.GetState(ctx, "kyc")
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+	if state.Metadata["interrupt_value"] != "date of birth?" {
+		t.Errorf("expected the decrypted interrupt value, got %v", state.Metadata["interrupt_value"])
+	}
+
+	result, err := runnable.InvokeWithConfig(ctx, graph.Resume("1990-01-01"), threadConfig("kyc"))
+	if err != nil || result != "jane 123-45-6789 1990-01-01" {
+		t.Errorf("expected the resume values to survive encryption, got %v (%v)", result, err)
+	}
+}
+
+func TestEncryptedCheckpointStore_PlainValues(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := graph.NewMemoryCheckpointStore()
+	store := graph.NewEncryptedCheckpointStore(backend, graph.NewKeyRing("k", bytes.Repeat([]byte{5}, 32)))
+
+	// Values that merely look encoded, saved before and after the decorator was added
+	plain := "aes-gcm:k:c2VjcmV0"
+	if err := backend.Save(ctx, &graph.Checkpoint{ID: "before", ThreadID: "t", State: plain, Metadata: map[string]interface{}{"interrupt_value": plain}}); err != nil {
+		t.Fatalf("failed to save checkpoint: %v", err)
+	}
+	if err := store.Save(ctx, &graph.Checkpoint{ID: "after", ThreadID: "t", State: plain, Metadata: map[string]interface{}{"interrupt_value": plain}}); err != nil {
+		t.Fatalf("failed to save checkpoint: %v", err)
+	}
+
+	for _, id := range []string{"before", "after"} {
+		loaded, err := store.Load(ctx, id)
+		if err != nil {
+			t.Fatalf("failed to load %s: %v", id, err)
+		}
+		if loaded.State != plain || loaded.Metadata["interrupt_value"] != plain || len(loaded.Metadata) != 1 {
+			t.Errorf("expected %s to round-trip unchanged, got %v %v", id, loaded.State, loaded.Metadata)
+		}
+	}
+}
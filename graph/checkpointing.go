@@ -2,11 +2,13 @@ package graph
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Checkpoint represents a saved state at a specific point in execution
@@ -17,6 +19,37 @@ type Checkpoint struct {
 	Metadata  map[string]interface{} `json:"metadata"`
 	Timestamp time.Time              `json:"timestamp"`
 	Version   int                    `json:"version"`
+
+	// ThreadID identifies the conversation or run history the checkpoint belongs to
+	ThreadID string `json:"thread_id,omitempty"`
+
+	// ParentID is the ID of the checkpoint saved before this one in the same thread
+	ParentID string `json:"parent_id,omitempty"`
+
+	// Next lists the nodes that run when execution resumes from this checkpoint: the nodes
+	// of the step after the one the checkpoint was saved for, or of the step that was
+	// interrupted or failed. It is empty when the run finished here.
+	Next []string `json:"next,omitempty"`
+
+	// Sends lists the nodes that run with their own input, along with Next, when
+	// execution resumes from this checkpoint
+	Sends []Send `json:"sends,omitempty"`
+
+	// PendingWrites holds the outputs of the tasks that completed in the step that failed or
+	// was interrupted. Resuming from the checkpoint reuses them and only runs the tasks that
+	// did not complete.
+	PendingWrites []PendingWrite `json:"pending_writes,omitempty"`
+}
+
+// pending reports whether the checkpoint lists the nodes that run when execution resumes
+func (c *Checkpoint) pending() bool {
+	return len(c.Next) > 0 || len(c.Sends) > 0
+}
+
+// completedStep reports whether the checkpoint was saved after a step completed, rather
+// than for a run that paused or failed before its pending nodes ran
+func (c *Checkpoint) completedStep() bool {
+	return fmt.Sprint(c.Metadata["event"]) == string(NodeEventComplete)
 }
 
 // CheckpointStore defines the interface for checkpoint persistence
@@ -27,7 +60,7 @@ type CheckpointStore interface {
 	// Load retrieves a checkpoint by ID
 	Load(ctx context.Context, checkpointID string) (*Checkpoint, error)
 
-	// List returns all checkpoints for a given execution
+	// List returns all checkpoints for a given execution (thread)
 	List(ctx context.Context, executionID string) ([]*Checkpoint, error)
 
 	// Delete removes a checkpoint
@@ -79,7 +112,7 @@ func (m *MemoryCheckpointStore) List(_ context.Context, executionID string) ([]*
 
 	var checkpoints []*Checkpoint
 	for _, checkpoint := range m.checkpoints {
-		if execID, ok := checkpoint.Metadata["execution_id"].(string); ok && execID == executionID {
+		if checkpointThreadID(checkpoint) == executionID {
 			checkpoints = append(checkpoints, checkpoint)
 		}
 	}
@@ -102,7 +135,7 @@ func (m *MemoryCheckpointStore) Clear(_ context.Context, executionID string) err
 	defer m.mutex.Unlock()
 
 	for id, checkpoint := range m.checkpoints {
-		if execID, ok := checkpoint.Metadata["execution_id"].(string); ok && execID == executionID {
+		if checkpointThreadID(checkpoint) == executionID {
 			delete(m.checkpoints, id)
 		}
 	}
@@ -110,94 +143,93 @@ func (m *MemoryCheckpointStore) Clear(_ context.Context, executionID string) err
 	return nil
 }
 
-// FileCheckpointStore provides file-based checkpoint storage
-type FileCheckpointStore struct {
-	writer io.Writer
-	reader io.Reader
-	mutex  sync.RWMutex
-}
+// CheckpointConfig configures checkpointing behavior
+type CheckpointConfig struct {
+	// Store is the checkpoint storage backend
+	Store CheckpointStore
 
-// NewFileCheckpointStore creates a new file-based checkpoint store
-func NewFileCheckpointStore(writer io.Writer, reader io.Reader) *FileCheckpointStore {
-	return &FileCheckpointStore{
-		writer: writer,
-		reader: reader,
-	}
-}
+	// AutoSave enables automatic checkpointing after each step
+	AutoSave bool
 
-// Save implements CheckpointStore interface for file storage
-func (f *FileCheckpointStore) Save(_ context.Context, checkpoint *Checkpoint) error {
-	f.mutex.Lock()
-	defer f.mutex.Unlock()
+	// SaveInterval specifies how often to save (when AutoSave is false)
+	SaveInterval time.Duration
 
-	data, err := json.Marshal(checkpoint)
-	if err != nil {
-		return fmt.Errorf("failed to marshal checkpoint: %w", err)
-	}
+	// SaveMode selects which checkpoints are saved during a run.
+	// By default it follows AutoSave and SaveInterval.
+	SaveMode SaveMode
 
-	_, err = f.writer.Write(data)
-	if err != nil {
-		return fmt.Errorf("failed to write checkpoint: %w", err)
-	}
+	// MaxCheckpoints limits the number of checkpoints kept along each branch of a thread;
+	// older ones are deleted after each run. Zero keeps every checkpoint.
+	MaxCheckpoints int
 
-	return nil
-}
+	// KeepFirst keeps the first checkpoint of a thread when pruning to MaxCheckpoints
+	KeepFirst bool
 
-// Load implements CheckpointStore interface for file storage
-func (f *FileCheckpointStore) Load(_ context.Context, checkpointID string) (*Checkpoint, error) {
-	f.mutex.RLock()
-	defer f.mutex.RUnlock()
+	// KeepEvery keeps the checkpoints whose version is a multiple of KeepEvery when
+	// pruning to MaxCheckpoints
+	KeepEvery int
 
-	data, err := io.ReadAll(f.reader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
-	}
+	// Durability selects when checkpoints are written to the store during a run
+	Durability Durability
 
-	var checkpoint Checkpoint
-	err = json.Unmarshal(data, &checkpoint)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal checkpoint: %w", err)
-	}
+	// OnSaveError is called when a checkpoint cannot be saved during a run, which then
+	// continues. Without it, a failed save fails the run.
+	OnSaveError func(ctx context.Context, checkpoint *Checkpoint, err error)
+}
 
-	if checkpoint.ID != checkpointID {
-		return nil, fmt.Errorf("checkpoint not found: %s", checkpointID)
-	}
+// Durability selects when a CheckpointableRunnable writes checkpoints to its store
+type Durability int
 
-	return &checkpoint, nil
-}
+const (
+	// DurabilityAsync saves checkpoints in the background while the run continues, and
+	// waits for the saves to finish before the run returns
+	DurabilityAsync Durability = iota
 
-// List implements CheckpointStore interface for file storage
-func (f *FileCheckpointStore) List(_ context.Context, _ string) ([]*Checkpoint, error) {
-	// For file storage, this would typically involve reading from multiple files
-	// This is a simplified implementation
-	return nil, fmt.Errorf("list operation not implemented for file store")
-}
+	// DurabilitySync saves each checkpoint before the next step starts, so a completed
+	// step is never lost
+	DurabilitySync
 
-// Delete implements CheckpointStore interface for file storage
-func (f *FileCheckpointStore) Delete(_ context.Context, checkpointID string) error {
-	// For file storage, this would involve file system operations
-	return fmt.Errorf("delete operation not implemented for file store")
-}
+	// DurabilityExit keeps the checkpoints of a run in memory and saves them when the run
+	// returns, which is fastest but loses them all if the process dies mid-run
+	DurabilityExit
+)
 
-// Clear implements CheckpointStore interface for file storage
-func (f *FileCheckpointStore) Clear(_ context.Context, executionID string) error {
-	// For file storage, this would involve file system operations
-	return fmt.Errorf("clear operation not implemented for file store")
-}
+// SaveMode selects which checkpoints a CheckpointableRunnable saves during a run.
+// Checkpoints of interrupted runs are saved in every mode.
+type SaveMode int
 
-// CheckpointConfig configures checkpointing behavior
-type CheckpointConfig struct {
-	// Store is the checkpoint storage backend
-	Store CheckpointStore
+const (
+	// SaveModeDefault saves after every step when AutoSave is set, on SaveInterval when it
+	// is set, and otherwise only when a run is interrupted
+	SaveModeDefault SaveMode = iota
 
-	// AutoSave enables automatic checkpointing after each node
-	AutoSave bool
+	// SaveModeEveryNode saves a checkpoint after every step, holding the state merged from
+	// the updates of all its nodes
+	SaveModeEveryNode
 
-	// SaveInterval specifies how often to save (when AutoSave is false)
-	SaveInterval time.Duration
+	// SaveModeInterval saves a checkpoint after a step when SaveInterval has passed since the
+	// last save, and saves the final state when the run ends
+	SaveModeInterval
 
-	// MaxCheckpoints limits the number of checkpoints to keep
-	MaxCheckpoints int
+	// SaveModeOnExit saves a checkpoint only when a run is interrupted or ends
+	SaveModeOnExit
+
+	// saveModeInterruptsOnly saves checkpoints only when a run is interrupted
+	saveModeInterruptsOnly
+)
+
+// saveMode resolves SaveModeDefault to the mode implied by AutoSave and SaveInterval
+func (c CheckpointConfig) saveMode() SaveMode {
+	switch {
+	case c.SaveMode != SaveModeDefault:
+		return c.SaveMode
+	case c.AutoSave:
+		return SaveModeEveryNode
+	case c.SaveInterval > 0:
+		return SaveModeInterval
+	default:
+		return saveModeInterruptsOnly
+	}
 }
 
 // DefaultCheckpointConfig returns a default checkpoint configuration
@@ -210,7 +242,10 @@ func DefaultCheckpointConfig() CheckpointConfig {
 	}
 }
 
-// CheckpointableRunnable wraps a runnable with checkpointing capabilities
+// CheckpointableRunnable wraps a runnable with checkpointing capabilities.
+// Checkpoints are grouped by thread: the thread of an invocation is read from the
+// "thread_id" key of Config.Configurable, and invocations without one share a default
+// thread created for the runnable. Every checkpoint points to the one saved before it.
 type CheckpointableRunnable struct {
 	runnable *ListenableRunnable
 	config   CheckpointConfig
@@ -229,42 +264,278 @@ func NewCheckpointableRunnable(runnable *ListenableRunnable, config CheckpointCo
 
 // Invoke executes the graph with checkpointing
 func (cr *CheckpointableRunnable) Invoke(ctx context.Context, initialState interface{}) (interface{}, error) {
-	// Create checkpointing listener
-	checkpointListener := &CheckpointListener{
+	return cr.InvokeWithConfig(ctx, initialState, nil)
+}
+
+// InvokeWithConfig executes the graph with checkpointing and the given config.
+// When a breakpoint is hit, a checkpoint is saved for the pause and the state reached so far
+// is returned with a *GraphInterrupt whose CheckpointID can be passed to Resume.
+// Passing a *Command, such as Resume(value), as the initial state continues the interrupted
+// run at the head of the thread instead of starting a new one. Passing a nil initial state to
+// a thread with checkpoints continues it from its latest checkpoint, or from the checkpoint
+// named by the "checkpoint_id" configurable, which forks the thread when that checkpoint is
+// not the latest one.
+func (cr *CheckpointableRunnable) InvokeWithConfig(ctx context.Context, initialState interface{}, config *Config) (interface{}, error) {
+	ctx, cancel := withConfig(ctx, config)
+	defer cancel()
+
+	threadID := cr.threadID(config)
+
+	if command, ok := initialState.(*Command); ok {
+		return cr.invokeCommand(ctx, config, threadID, command)
+	}
+
+	head, err := cr.head(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	if initialState == nil && (head != nil || cr.checkpointID(config) != "") {
+		return cr.continueThread(ctx, config, threadID, head)
+	}
+
+	parentID := ""
+	if head != nil {
+		parentID = head.ID
+	}
+
+	return cr.execute(ctx, config, threadID, parentID, func(ctx context.Context, engine *superstepEngine[interface{}]) (interface{}, error) {
+		return engine.start(ctx, initialState, config)
+	})
+}
+
+// invokeCommand resumes the interrupted run at the head of the thread, passing the
+// command's resume value to the Interrupt call that suspended it
+func (cr *CheckpointableRunnable) invokeCommand(ctx context.Context, config *Config, threadID string, command *Command) (interface{}, error) {
+	checkpoint, err := cr.head(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint == nil || !checkpoint.pending() {
+		return nil, fmt.Errorf("no interrupted run to resume in thread %s", threadID)
+	}
+
+	// Earlier resume values are replayed so that a node calling Interrupt several
+	// times receives one value per call
+	previous, _ := checkpoint.Metadata["resume_values"].([]interface{})
+	resumeValues := append(append([]interface{}(nil), previous...), command.Resume)
+
+	// The values go to the task that called Interrupt, which is the node itself unless
+	// the node was reached through a Send
+	taskID, _ := checkpoint.Metadata["interrupt_task"].(string)
+	if taskID == "" {
+		taskID = checkpoint.NodeName
+	}
+
+	return cr.execute(ctx, config, threadID, checkpoint.ID, func(ctx context.Context, engine *superstepEngine[interface{}]) (interface{}, error) {
+		engine.resumeValues = map[string][]interface{}{taskID: resumeValues}
+		engine.pendingWrites = checkpoint.PendingWrites
+		return engine.resume(ctx, checkpoint.State, checkpoint.Next, checkpoint.Sends)
+	})
+}
+
+// continueThread runs the graph from a checkpoint of the thread instead of from the entry point:
+// the checkpoint named in the config, or else the head of the thread
+func (cr *CheckpointableRunnable) continueThread(ctx context.Context, config *Config, threadID string, head *Checkpoint) (interface{}, error) {
+	checkpoint := head
+	if checkpointID := cr.checkpointID(config); checkpointID != "" {
+		loaded, err := cr.LoadCheckpoint(ctx, checkpointID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+		}
+		if checkpointThreadID(loaded) != threadID {
+			return nil, fmt.Errorf("checkpoint %s does not belong to thread %s", checkpointID, threadID)
+		}
+		checkpoint = loaded
+	}
+
+	return cr.continueFrom(ctx, config, checkpoint)
+}
+
+// continueFrom runs the graph from a checkpoint: its pending nodes if the run was
+// interrupted or failed there, or else the nodes following the checkpointed node
+func (cr *CheckpointableRunnable) continueFrom(ctx context.Context, config *Config, checkpoint *Checkpoint) (interface{}, error) {
+	return cr.execute(ctx, config, checkpointThreadID(checkpoint), checkpoint.ID, func(ctx context.Context, engine *superstepEngine[interface{}]) (interface{}, error) {
+		if checkpoint.pending() {
+			return resumeCheckpoint(ctx, engine, checkpoint, checkpoint.State, checkpoint.PendingWrites)
+		}
+		return engine.resumeAfter(ctx, checkpoint.State, checkpoint.NodeName)
+	})
+}
+
+// resumeCheckpoint runs the pending nodes of a checkpoint with the given state. Breakpoints
+// before them apply when the checkpoint was saved after a completed step, and are skipped
+// when the run already paused or failed there.
+func resumeCheckpoint(ctx context.Context, engine *superstepEngine[interface{}], checkpoint *Checkpoint, state interface{}, pendingWrites []PendingWrite) (interface{}, error) {
+	if checkpoint.completedStep() {
+		return engine.continueWith(ctx, state, checkpoint.Next, checkpoint.Sends)
+	}
+
+	engine.pendingWrites = pendingWrites
+	return engine.resume(ctx, state, checkpoint.Next, checkpoint.Sends)
+}
+
+// Resume continues a run that was interrupted, from the checkpoint saved for the pause.
+// It also retries a run that failed, from the checkpoint saved for the failed step: the
+// tasks that completed in that step are not run again.
+func (cr *CheckpointableRunnable) Resume(ctx context.Context, checkpointID string) (interface{}, error) {
+	checkpoint, err := cr.loadInterruptCheckpoint(ctx, checkpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	return cr.resume(ctx, checkpoint, checkpoint.State, checkpoint.PendingWrites)
+}
+
+// ResumeWithState continues a run that was interrupted, replacing the state saved for the
+// pause with the given state, e.g. after a person reviewed and edited it.
+// The pending writes of a failed step were produced from the saved state, so every task
+// of the step runs again.
+func (cr *CheckpointableRunnable) ResumeWithState(ctx context.Context, checkpointID string, state interface{}) (interface{}, error) {
+	checkpoint, err := cr.loadInterruptCheckpoint(ctx, checkpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	return cr.resume(ctx, checkpoint, state, nil)
+}
+
+// loadInterruptCheckpoint loads a checkpoint and checks that it has nodes left to run
+func (cr *CheckpointableRunnable) loadInterruptCheckpoint(ctx context.Context, checkpointID string) (*Checkpoint, error) {
+	checkpoint, err := cr.LoadCheckpoint(ctx, checkpointID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+
+	if !checkpoint.pending() {
+		return nil, fmt.Errorf("checkpoint %s has no pending nodes to resume", checkpointID)
+	}
+
+	return checkpoint, nil
+}
+
+// resume runs the graph from the pending nodes of an interrupt checkpoint with the given
+// state, reusing the given writes of the tasks that already completed
+func (cr *CheckpointableRunnable) resume(ctx context.Context, checkpoint *Checkpoint, state interface{}, pendingWrites []PendingWrite) (interface{}, error) {
+	return cr.execute(ctx, nil, checkpointThreadID(checkpoint), checkpoint.ID, func(ctx context.Context, engine *superstepEngine[interface{}]) (interface{}, error) {
+		return resumeCheckpoint(ctx, engine, checkpoint, state, pendingWrites)
+	})
+}
+
+// execute runs the graph, saving the checkpoints of the run to the given thread after the
+// given parent checkpoint.
+// It also saves a checkpoint when the run is interrupted, or when it fails after some tasks
+// of the failed step completed, and waits for pending saves.
+func (cr *CheckpointableRunnable) execute(ctx context.Context, config *Config, threadID, parentID string, run func(ctx context.Context, engine *superstepEngine[interface{}]) (interface{}, error)) (interface{}, error) {
+	version, err := cr.latestVersion(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := &checkpointWriter{
 		store:       cr.config.Store,
-		executionID: cr.executionID,
-		autoSave:    cr.config.AutoSave,
+		threadID:    threadID,
+		parentID:    parentID,
+		version:     version,
+		lastSaved:   time.Now(),
+		durability:  cr.config.Durability,
+		onSaveError: cr.config.OnSaveError,
 	}
+	defer writer.wait()
 
-	// Add checkpoint listener to all nodes
-	for _, node := range cr.runnable.listenableNodes {
-		node.AddListener(checkpointListener)
+	hook := checkpointHook{
+		writer:   writer,
+		mode:     cr.config.saveMode(),
+		interval: cr.config.SaveInterval,
 	}
 
-	defer func() {
-		// Clean up: remove checkpoint listener from all nodes
-		for _, node := range cr.runnable.listenableNodes {
-			node.RemoveListener(checkpointListener)
+	engine := cr.runnable.newEngine(config)
+	engine.hooks = append([]runHook[interface{}]{hook}, engine.hooks...)
+
+	// The writes of a failed step are saved with the step's input, so that resuming
+	// only runs the tasks that did not complete
+	var failed *Checkpoint
+	engine.onStepError = func(_ context.Context, state interface{}, active []string, sends []Send, node string, writes []PendingWrite) {
+		failed = &Checkpoint{
+			NodeName:      node,
+			State:         state,
+			Next:          append([]string(nil), active...),
+			Sends:         append([]Send(nil), sends...),
+			PendingWrites: writes,
 		}
-	}()
+	}
 
-	return cr.runnable.Invoke(ctx, initialState)
+	state, err := run(ctx, engine)
+
+	var interrupt *GraphInterrupt
+	interrupted := errors.As(err, &interrupt)
+	if interrupted {
+		metadata := map[string]interface{}{
+			"event": "interrupt",
+		}
+		if interrupt.dynamic {
+			metadata["interrupt_value"] = interrupt.Value
+			metadata["interrupt_task"] = interrupt.taskID
+			metadata["resume_values"] = interrupt.resumeValues
+		}
+
+		checkpoint := writer.next(interrupt.Node, interrupt.State, metadata)
+		checkpoint.Next = interrupt.Next
+		checkpoint.Sends = interrupt.Sends
+		checkpoint.PendingWrites = interrupt.writes
+
+		writer.save(ctx, checkpoint)
+		interrupt.CheckpointID = checkpoint.ID
+	} else if err != nil && failed != nil {
+		checkpoint := writer.next(failed.NodeName, failed.State, map[string]interface{}{
+			"event": "error",
+			"error": err.Error(),
+		})
+		checkpoint.Next = failed.Next
+		checkpoint.Sends = failed.Sends
+		checkpoint.PendingWrites = failed.PendingWrites
+
+		writer.save(ctx, checkpoint)
+	}
+
+	if err == nil && hook.saveOnExit() {
+		writer.save(ctx, writer.next(writer.lastNode(), state, map[string]interface{}{
+			"event": "exit",
+		}))
+	}
+
+	// Pending saves must land before older checkpoints are pruned
+	if saveErr := writer.flush(ctx); saveErr != nil && (err == nil || interrupted) {
+		return state, saveErr
+	}
+	if pruneErr := cr.prune(ctx, threadID); pruneErr != nil && err == nil {
+		return state, pruneErr
+	}
+
+	return state, err
 }
 
-// SaveCheckpoint manually saves a checkpoint
+// SaveCheckpoint manually saves a checkpoint to the runnable's default thread
 func (cr *CheckpointableRunnable) SaveCheckpoint(ctx context.Context, nodeName string, state interface{}) error {
-	checkpoint := &Checkpoint{
-		ID:        generateCheckpointID(),
-		NodeName:  nodeName,
-		State:     state,
-		Timestamp: time.Now(),
-		Version:   1,
-		Metadata: map[string]interface{}{
-			"execution_id": cr.executionID,
-		},
+	head, err := cr.head(ctx, cr.executionID)
+	if err != nil {
+		return err
+	}
+
+	writer := &checkpointWriter{
+		store:    cr.config.Store,
+		threadID: cr.executionID,
+	}
+	if head != nil {
+		writer.parentID = head.ID
+		writer.version = head.Version
 	}
 
-	return cr.config.Store.Save(ctx, checkpoint)
+	if err := cr.config.Store.Save(ctx, writer.next(nodeName, state, nil)); err != nil {
+		return err
+	}
+
+	return cr.prune(ctx, cr.executionID)
 }
 
 // LoadCheckpoint loads a specific checkpoint
@@ -272,68 +543,281 @@ func (cr *CheckpointableRunnable) LoadCheckpoint(ctx context.Context, checkpoint
 	return cr.config.Store.Load(ctx, checkpointID)
 }
 
-// ListCheckpoints returns all checkpoints for this execution
+// ListCheckpoints returns all checkpoints of the runnable's default thread
 func (cr *CheckpointableRunnable) ListCheckpoints(ctx context.Context) ([]*Checkpoint, error) {
 	return cr.config.Store.List(ctx, cr.executionID)
 }
 
-// ResumeFromCheckpoint resumes execution from a specific checkpoint
+// ResumeFromCheckpoint resumes execution from a specific checkpoint and returns the final state.
+// Execution continues with the nodes that follow the checkpointed node, or with the pending
+// nodes of a checkpoint saved when a run was interrupted.
 func (cr *CheckpointableRunnable) ResumeFromCheckpoint(ctx context.Context, checkpointID string) (interface{}, error) {
 	checkpoint, err := cr.LoadCheckpoint(ctx, checkpointID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
 	}
 
-	// Resume execution from the checkpointed state
-	// This would require the graph to support starting from a specific node
-	// For now, we'll return the checkpointed state
-	return checkpoint.State, nil
+	return cr.continueFrom(ctx, nil, checkpoint)
 }
 
-// ClearCheckpoints removes all checkpoints for this execution
+// ClearCheckpoints removes all checkpoints of the runnable's default thread
 func (cr *CheckpointableRunnable) ClearCheckpoints(ctx context.Context) error {
 	return cr.config.Store.Clear(ctx, cr.executionID)
 }
 
-// CheckpointListener automatically creates checkpoints during execution
-type CheckpointListener struct {
-	store       CheckpointStore
-	executionID string
-	autoSave    bool
+// threadID returns the thread of an invocation: the "thread_id" configurable of the
+// config, or the runnable's default thread
+func (cr *CheckpointableRunnable) threadID(config *Config) string {
+	if config != nil {
+		if threadID, ok := config.Configurable["thread_id"].(string); ok && threadID != "" {
+			return threadID
+		}
+	}
+	return cr.executionID
 }
 
-// OnNodeEvent implements the NodeListener interface for checkpointing
-func (cl *CheckpointListener) OnNodeEvent(ctx context.Context, event NodeEvent, nodeName string, state interface{}, err error) {
-	if !cl.autoSave || event != NodeEventComplete {
-		return
+// checkpointID returns the "checkpoint_id" configurable of the config, if any
+func (cr *CheckpointableRunnable) checkpointID(config *Config) string {
+	if config == nil {
+		return ""
 	}
+	checkpointID, _ := config.Configurable["checkpoint_id"].(string)
+	return checkpointID
+}
 
+// history returns the checkpoints of a thread, newest first
+func (cr *CheckpointableRunnable) history(ctx context.Context, threadID string) ([]*Checkpoint, error) {
+	checkpoints, err := cr.config.Store.List(ctx, threadID)
 	if err != nil {
-		// Don't save checkpoints for failed nodes
-		return
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
 	}
 
+	sorted := append([]*Checkpoint(nil), checkpoints...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Version != sorted[j].Version {
+			return sorted[i].Version > sorted[j].Version
+		}
+		return sorted[i].Timestamp.After(sorted[j].Timestamp)
+	})
+
+	return sorted, nil
+}
+
+// head returns the latest checkpoint of a thread, or nil if the thread has none
+func (cr *CheckpointableRunnable) head(ctx context.Context, threadID string) (*Checkpoint, error) {
+	checkpoints, err := cr.history(ctx, threadID)
+	if err != nil || len(checkpoints) == 0 {
+		return nil, err
+	}
+	return checkpoints[0], nil
+}
+
+// latestVersion returns the highest checkpoint version of a thread
+func (cr *CheckpointableRunnable) latestVersion(ctx context.Context, threadID string) (int, error) {
+	head, err := cr.head(ctx, threadID)
+	if err != nil || head == nil {
+		return 0, err
+	}
+	return head.Version, nil
+}
+
+// checkpointWriter creates the checkpoints of one run. Each checkpoint gets the next
+// version of the thread and points to the checkpoint created before it.
+type checkpointWriter struct {
+	store    CheckpointStore
+	threadID string
+
+	durability  Durability
+	onSaveError func(ctx context.Context, checkpoint *Checkpoint, err error)
+
+	mutex     sync.Mutex
+	parentID  string
+	version   int
+	completed string
+	lastSaved time.Time
+	unsaved   bool
+	buffered  []*Checkpoint
+	err       error
+
+	pending sync.WaitGroup
+}
+
+// next creates the next checkpoint of the run; it does not save it
+func (w *checkpointWriter) next(nodeName string, state interface{}, metadata map[string]interface{}) *Checkpoint {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.version++
 	checkpoint := &Checkpoint{
 		ID:        generateCheckpointID(),
+		ThreadID:  w.threadID,
+		ParentID:  w.parentID,
 		NodeName:  nodeName,
 		State:     state,
 		Timestamp: time.Now(),
-		Version:   1,
+		Version:   w.version,
 		Metadata: map[string]interface{}{
-			"execution_id": cl.executionID,
-			"event":        event,
+			"execution_id": w.threadID,
 		},
 	}
+	for key, value := range metadata {
+		checkpoint.Metadata[key] = value
+	}
 
-	// Save checkpoint asynchronously to avoid blocking execution
-	go func(ctx context.Context) {
-		if saveErr := cl.store.Save(ctx, checkpoint); saveErr != nil {
-			// Error is intentionally ignored to avoid blocking execution
-			_ = saveErr
-		}
-	}(ctx)
+	w.parentID = checkpoint.ID
+	w.lastSaved = checkpoint.Timestamp
+	w.unsaved = false
+	return checkpoint
+}
+
+// complete records that a node of the run completed. It reports whether the last
+// checkpoint was created at least interval ago.
+func (w *checkpointWriter) complete(nodeName string, interval time.Duration) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.completed = nodeName
+	w.unsaved = true
+	return time.Since(w.lastSaved) >= interval
+}
+
+// lastNode returns the node that completed last in the run
+func (w *checkpointWriter) lastNode() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.completed
+}
+
+// hasUnsaved reports whether nodes completed since the last checkpoint was created
+func (w *checkpointWriter) hasUnsaved() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.unsaved
+}
+
+// save saves a checkpoint according to the writer's durability
+func (w *checkpointWriter) save(ctx context.Context, checkpoint *Checkpoint) {
+	switch w.durability {
+	case DurabilitySync:
+		w.write(ctx, checkpoint)
+	case DurabilityExit:
+		w.mutex.Lock()
+		w.buffered = append(w.buffered, checkpoint)
+		w.mutex.Unlock()
+	default:
+		w.pending.Add(1)
+		go func() {
+			defer w.pending.Done()
+			w.write(ctx, checkpoint)
+		}()
+	}
+}
+
+// write saves a checkpoint to the store, reporting a failure to the error handler or
+// recording it to fail the run
+func (w *checkpointWriter) write(ctx context.Context, checkpoint *Checkpoint) {
+	err := w.store.Save(ctx, checkpoint)
+	if err == nil {
+		return
+	}
+
+	if w.onSaveError != nil {
+		w.onSaveError(ctx, checkpoint, err)
+		return
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.err == nil {
+		w.err = fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+}
+
+// failure returns the first error recorded by a failed save
+func (w *checkpointWriter) failure() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.err
+}
+
+// flush waits for pending saves, writes buffered checkpoints in order, and returns the
+// first error recorded by a failed save
+func (w *checkpointWriter) flush(ctx context.Context) error {
+	w.wait()
+
+	w.mutex.Lock()
+	buffered := w.buffered
+	w.buffered = nil
+	w.mutex.Unlock()
+
+	for _, checkpoint := range buffered {
+		w.write(ctx, checkpoint)
+	}
+
+	return w.failure()
 }
 
+// wait blocks until every asynchronous save has finished
+func (w *checkpointWriter) wait() {
+	w.pending.Wait()
+}
+
+// checkpointHook saves a checkpoint after the steps of a run selected by the save mode,
+// holding the state merged from the updates of the step's nodes and the nodes of the next
+// step. A checkpoint that failed to save stops the run after the step that produced it.
+type checkpointHook struct {
+	baseHook[interface{}]
+	writer   *checkpointWriter
+	mode     SaveMode
+	interval time.Duration
+}
+
+func (h checkpointHook) endStep(ctx context.Context, nodes []string, state interface{}, next []string, sends []Send) error {
+	// The checkpoint is named after the last node of the step
+	node := nodes[len(nodes)-1]
+
+	due := h.writer.complete(node, h.interval)
+	if h.mode == SaveModeEveryNode || (h.mode == SaveModeInterval && due) {
+		checkpoint := h.writer.next(node, state, map[string]interface{}{
+			"event": NodeEventComplete,
+			"nodes": nodes,
+		})
+		checkpoint.Next = next
+		checkpoint.Sends = sends
+
+		h.writer.save(ctx, checkpoint)
+	}
+
+	if err := h.writer.failure(); err != nil {
+		return fmt.Errorf("error in node %s: %w", node, err)
+	}
+	return nil
+}
+
+// saveOnExit reports whether the final state of a successful run must be saved
+func (h checkpointHook) saveOnExit() bool {
+	switch h.mode {
+	case SaveModeOnExit:
+		return true
+	case SaveModeInterval:
+		return h.writer.hasUnsaved()
+	default:
+		return false
+	}
+}
+
+// CheckpointListener used to save a checkpoint for every node that completed.
+//
+// Deprecated: a CheckpointableRunnable saves one checkpoint per step, holding the state
+// merged from the updates of all its nodes, without a listener. OnNodeEvent does nothing.
+type CheckpointListener struct{}
+
+// OnNodeEvent implements the NodeListener interface
+func (cl *CheckpointListener) OnNodeEvent(context.Context, NodeEvent, string, interface{}, error) {}
+
 // CheckpointableMessageGraph extends ListenableMessageGraph with checkpointing
 type CheckpointableMessageGraph struct {
 	*ListenableMessageGraph
@@ -357,8 +841,8 @@ func NewCheckpointableMessageGraphWithConfig(config CheckpointConfig) *Checkpoin
 }
 
 // CompileCheckpointable compiles the graph into a checkpointable runnable
-func (g *CheckpointableMessageGraph) CompileCheckpointable() (*CheckpointableRunnable, error) {
-	listenableRunnable, err := g.CompileListenable()
+func (g *CheckpointableMessageGraph) CompileCheckpointable(opts ...CompileOption) (*CheckpointableRunnable, error) {
+	listenableRunnable, err := g.CompileListenable(opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -382,5 +866,14 @@ func generateExecutionID() string {
 }
 
 func generateCheckpointID() string {
-	return fmt.Sprintf("checkpoint_%d", time.Now().UnixNano())
+	return fmt.Sprintf("checkpoint_%s", uuid.New().String())
+}
+
+// checkpointThreadID returns the thread a checkpoint belongs to
+func checkpointThreadID(checkpoint *Checkpoint) string {
+	if checkpoint.ThreadID != "" {
+		return checkpoint.ThreadID
+	}
+	threadID, _ := checkpoint.Metadata["execution_id"].(string)
+	return threadID
 }
@@ -688,3 +688,127 @@ func TestCheckpointListener_ErrorHandling(t *testing.T) {
 		t.Errorf("Expected no checkpoints for failed execution, got %d", len(checkpoints))
 	}
 }
+
+func TestCheckpointableRunnable_ResumeFromCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewCheckpointableMessageGraph()
+	g.AddNode("increment", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state.(int) + 1, nil
+	})
+	g.AddNode("double", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state.(int) * 2, nil
+	})
+	g.AddConditionalEdge("increment", func(_ context.Context, state interface{}) string {
+		if state.(int) < 3 {
+			return "increment"
+		}
+		return "double"
+	})
+	g.AddEdge("double", graph.END)
+	g.SetEntryPoint("increment")
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	expected, err := runnable.Invoke(ctx, 0)
+	if err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+	if expected != 6 {
+		t.Fatalf("Expected 6, got %v", expected)
+	}
+
+	// Wait for async checkpoint operations
+	time.Sleep(100 * time.Millisecond)
+
+	checkpoints, err := runnable.ListCheckpoints(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list checkpoints: %v", err)
+	}
+
+	for _, checkpoint := range checkpoints {
+		result, err := runnable.ResumeFromCheckpoint(ctx, checkpoint.ID)
+		if err != nil {
+			t.Fatalf("Failed to resume from %s checkpoint with state %v: %v", checkpoint.NodeName, checkpoint.State, err)
+		}
+		if result != expected {
+			t.Errorf("Resuming from %s checkpoint with state %v: expected %v, got %v",
+				checkpoint.NodeName, checkpoint.State, expected, result)
+		}
+	}
+}
+
+func TestCheckpointableRunnable_ResumeParallelStep(t *testing.T) {
+	t.Parallel()
+
+	visit := func(name string) func(context.Context, interface{}) (interface{}, error) {
+		return func(_ context.Context, state interface{}) (interface{}, error) {
+			visited := append([]string(nil), state.([]string)...)
+			return append(visited, name), nil
+		}
+	}
+
+	g := graph.NewCheckpointableMessageGraph()
+	for _, name := range []string{"a", "b", "c", "d"} {
+		g.AddNode(name, visit(name))
+	}
+	g.AddEdge("a", "b")
+	g.AddEdge("a", "c")
+	g.AddEdge("b", "d")
+	g.AddEdge("c", "d")
+	g.AddEdge("d", graph.END)
+	g.SetEntryPoint("a")
+
+	// Each update appends the name of its node to the current state
+	g.SetStateMerger(func(_ context.Context, current interface{}, updates []interface{}) (interface{}, error) {
+		merged := append([]string(nil), current.([]string)...)
+		for _, update := range updates {
+			merged = append(merged, update.([]string)[len(current.([]string)):]...)
+		}
+		return merged, nil
+	})
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := runnable.Invoke(ctx, []string{}); err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+
+	checkpoints, err := runnable.ListCheckpoints(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list checkpoints: %v", err)
+	}
+	if len(checkpoints) != 3 {
+		t.Fatalf("Expected one checkpoint per step, got %d", len(checkpoints))
+	}
+
+	// The checkpoint of the parallel step holds the updates of both branches
+	var parallel *graph.Checkpoint
+	for _, checkpoint := range checkpoints {
+		if len(checkpoint.Next) == 1 && checkpoint.Next[0] == "d" {
+			parallel = checkpoint
+		}
+	}
+	if parallel == nil {
+		t.Fatal("Missing the checkpoint of the parallel step")
+	}
+	if got := fmt.Sprint(parallel.State); got != "[a b c]" {
+		t.Errorf("Expected the merged state [a b c], got %s", got)
+	}
+
+	result, err := runnable.ResumeFromCheckpoint(ctx, parallel.ID)
+	if err != nil {
+		t.Fatalf("Failed to resume: %v", err)
+	}
+	if got := fmt.Sprint(result); got != "[a b c d]" {
+		t.Errorf("Expected [a b c d], got %s", got)
+	}
+}
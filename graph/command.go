@@ -0,0 +1,78 @@
+package graph
+
+import "fmt"
+
+// CommandParent is the Graph of a command that is routed by the parent of the graph
+// whose node returned it, e.g. to hand off from an agent running as a Subgraph to
+// another agent of the parent graph
+const CommandParent = "__parent__"
+
+// Command is passed to a checkpointable runnable in place of the input state to
+// control how an interrupted run continues.
+//
+// A node of a graph with an untyped state can also return a Command in place of its
+// state, to update the state and choose the nodes that run next in one step.
+type Command struct {
+	// Resume is returned by the Interrupt call that suspended the run
+	Resume interface{}
+
+	// Update is applied to the state as if the node had returned it.
+	// A nil Update leaves the state unchanged.
+	Update interface{}
+
+	// Goto names the node, as a string, or nodes, as a []string, that run next in place
+	// of the targets of the node's edges. It can also hold a Send, or a []Send, to run
+	// nodes with their own input. The node's edges are followed when it is nil.
+	Goto interface{}
+
+	// Graph is the graph the command applies to: the graph of the node when empty, or
+	// its parent graph for CommandParent
+	Graph string
+}
+
+// Resume creates a command that continues an interrupted run, making the pending
+// Interrupt call return the given value
+func Resume(value interface{}) *Command {
+	return &Command{Resume: value}
+}
+
+// targets returns the nodes and sends named by Goto
+func (c *Command) targets() ([]string, []Send, error) {
+	switch target := c.Goto.(type) {
+	case nil:
+		return nil, nil, nil
+	case string:
+		return []string{target}, nil, nil
+	case []string:
+		return target, nil, nil
+	case Send:
+		return nil, []Send{target}, nil
+	case []Send:
+		return nil, target, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported command Goto of type %T", c.Goto)
+	}
+}
+
+// commandOf returns the command a node returned in place of its state, if any
+func commandOf(value interface{}) (*Command, bool) {
+	switch command := value.(type) {
+	case *Command:
+		return command, command != nil
+	case Command:
+		return &command, true
+	default:
+		return nil, false
+	}
+}
+
+// parentCommandError is returned by a graph whose node returned a command for the
+// parent graph. Subgraph turns it back into a command for the parent.
+type parentCommandError struct {
+	node    string
+	command *Command
+}
+
+func (e *parentCommandError) Error() string {
+	return fmt.Sprintf("node %s returned a command for the parent graph, but the graph is not running as a subgraph", e.node)
+}
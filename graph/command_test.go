@@ -0,0 +1,200 @@
+package graph_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+func TestCommand_UpdateAndGoto(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		command  graph.Command
+		expected string
+	}{
+		{name: "goto one node", command: graph.Command{Update: "routed", Goto: "billing"}, expected: "routed>billing"},
+		{name: "goto several nodes", command: graph.Command{Update: "routed", Goto: []string{"billing", "support"}}, expected: "routed>billing>support"},
+		{name: "goto end", command: graph.Command{Update: "routed", Goto: graph.END}, expected: "routed"},
+		{name: "no update", command: graph.Command{Goto: "support"}, expected: "input>support"},
+		{name: "no goto follows edges", command: graph.Command{Update: "routed"}, expected: "routed>fallback"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			g := graph.NewMessageGraph()
+			g.AddNode("router", func(_ context.Context, _ interface{}) (interface{}, error) {
+				return tt.command, nil
+			})
+			for _, name := range []string{"billing", "support", "fallback"} {
+				g.AddNode(name, func(_ context.Context, state interface{}) (interface{}, error) {
+					return state.(string) + ">" + name, nil
+				})
+				g.AddEdge(name, graph.END)
+			}
+			g.AddEdge("router", "fallback")
+			g.SetEntryPoint("router")
+			g.SetStateMerger(func(_ context.Context, current interface{}, updates []interface{}) (interface{}, error) {
+				merged := current.(string)
+				for _, update := range updates {
+					merged += strings.TrimPrefix(update.(string), current.(string))
+				}
+				return merged, nil
+			})
+
+			runnable, err := g.Compile()
+			if err != nil {
+				t.Fatalf("failed to compile: %v", err)
+			}
+
+			result, err := runnable.Invoke(context.Background(), "input")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestCommand_StateSchema(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewStateGraph()
+	g.SetSchema(graph.NewMapSchema().AddChannel("visited", graph.AppendReducer))
+	g.AddNode("triage", func(_ context.Context, _ interface{}) (interface{}, error) {
+		return &graph.Command{
+			Update: map[string]interface{}{"visited": []interface{}{"triage"}, "queue": "urgent"},
+			Goto:   "urgent",
+		}, nil
+	})
+	g.AddNode("urgent", func(_ context.Context, _ interface{}) (interface{}, error) {
+		return map[string]interface{}{"visited": []interface{}{"urgent"}}, nil
+	})
+	g.AddEdge("urgent", graph.END)
+	g.SetEntryPoint("triage")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	state := result.(map[string]interface{})
+	if state["queue"] != "urgent" || !reflect.DeepEqual(state["visited"], []interface{}{"triage", "urgent"}) {
+		t.Errorf("unexpected state: %v", state)
+	}
+}
+
+func TestCommand_ParentGraph(t *testing.T) {
+	t.Parallel()
+
+	// The triage agent hands the conversation off to the billing agent of the parent graph
+	agent := graph.NewMessageGraph()
+	agent.AddNode("triage", func(_ context.Context, state interface{}) (interface{}, error) {
+		return &graph.Command{Update: state.(string) + ">triage", Goto: "billing", Graph: graph.CommandParent}, nil
+	})
+	agent.AddNode("answer", func(_ context.Context, state interface{}) (interface{}, error) {
+		t.Error("expected the subgraph to stop after the handoff")
+		return state, nil
+	})
+	agent.AddEdge("triage", "answer")
+	agent.AddEdge("answer", graph.END)
+	agent.SetEntryPoint("triage")
+
+	g := graph.NewMessageGraph()
+	if err := g.AddSubgraph("triage_agent", agent); err != nil {
+		t.Fatalf("failed to add subgraph: %v", err)
+	}
+	g.AddNode("billing", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state.(string) + ">billing", nil
+	})
+	g.AddNode("support", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state.(string) + ">support", nil
+	})
+	g.AddEdge("triage_agent", "support")
+	g.AddEdge("billing", graph.END)
+	g.AddEdge("support", graph.END)
+	g.SetEntryPoint("triage_agent")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "hello>triage>billing" {
+		t.Errorf("expected the parent graph to route to billing, got %v", result)
+	}
+
+	// The top-level graph has no parent to route a command
+	standalone, err := agent.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+	if _, err := standalone.Invoke(context.Background(), "hello"); err == nil || !strings.Contains(err.Error(), "not running as a subgraph") {
+		t.Errorf("expected an error for a parent command at the top level, got %v", err)
+	}
+}
+
+func TestCommand_Checkpointing(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	g := graph.NewCheckpointableMessageGraphWithConfig(graph.CheckpointConfig{
+		Store:    graph.NewMemoryCheckpointStore(),
+		AutoSave: true,
+	})
+	g.AddNode("router", func(_ context.Context, state interface{}) (interface{}, error) {
+		return graph.Command{Update: state.(int) + 1, Goto: "review"}, nil
+	})
+	g.AddNode("review", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state.(int) * 10, nil
+	})
+	g.AddNode("publish", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	g.AddEdge("router", "publish")
+	g.AddEdge("review", graph.END)
+	g.AddEdge("publish", graph.END)
+	g.SetEntryPoint("router")
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	if result, err := runnable.InvokeWithConfig(ctx, 1, threadConfig("routed")); err != nil || result != 20 {
+		t.Fatalf("expected the command to route to review, got %v (%v)", result, err)
+	}
+
+	history, err := runnable.GetStateHistory(ctx, "routed")
+	if err != nil || len(history) != 2 {
+		t.Fatalf("expected 2 checkpoints, got %d (%v)", len(history), err)
+	}
+	router := history[1]
+	if router.Values != 2 || !reflect.DeepEqual(router.Next, []string{"review"}) {
+		t.Errorf("expected the command's update and target to be saved, got %v -> %v", router.Values, router.Next)
+	}
+
+	// Continuing from the router's checkpoint follows the command again
+	config := threadConfig("routed")
+	config.Configurable["checkpoint_id"] = router.CheckpointID
+	if result, err := runnable.InvokeWithConfig(ctx, nil, config); err != nil || result != 20 {
+		t.Errorf("expected the forked run to follow the command to review, got %v (%v)", result, err)
+	}
+}
@@ -2,6 +2,7 @@ package graph_test
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 
@@ -323,3 +324,112 @@ func TestConditionalEdges_ChainedConditions(t *testing.T) {
 		t.Errorf("Expected result -10, got %v", result)
 	}
 }
+
+func TestConditionalEdges_PathMap(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewStateGraph()
+	g.AddNode("classify", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	for _, name := range []string{"refund", "escalate"} {
+		g.AddNode(name, func(_ context.Context, state interface{}) (interface{}, error) {
+			return map[string]interface{}{"handled_by": name}, nil
+		})
+		g.AddEdge(name, graph.END)
+	}
+	g.AddConditionalEdgeWithPathMap("classify", func(_ context.Context, state interface{}) string {
+		return state.(map[string]interface{})["intent"].(string)
+	}, map[string]string{"money": "refund", "angry": "escalate", "other": graph.END})
+	g.SetEntryPoint("classify")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	tests := []struct {
+		intent   string
+		expected interface{}
+	}{
+		{intent: "money", expected: "refund"},
+		{intent: "angry", expected: "escalate"},
+		{intent: "other", expected: nil},
+	}
+	for _, tt := range tests {
+		result, err := runnable.Invoke(context.Background(), map[string]interface{}{"intent": tt.intent})
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", tt.intent, err)
+		}
+		if handledBy := result.(map[string]interface{})["handled_by"]; handledBy != tt.expected {
+			t.Errorf("expected %s to be handled by %v, got %v", tt.intent, tt.expected, handledBy)
+		}
+	}
+
+	// A key missing from the path map fails the run
+	_, err = runnable.Invoke(context.Background(), map[string]interface{}{"intent": "unknown"})
+	if err == nil || !strings.Contains(err.Error(), `returned "unknown"`) {
+		t.Errorf("expected an error for an unknown path, got %v", err)
+	}
+}
+
+func TestConditionalEdges_TargetValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		declare func(g *graph.MessageGraph, condition func(context.Context, interface{}) string)
+		invalid string
+	}{
+		{
+			name: "targets",
+			declare: func(g *graph.MessageGraph, condition func(context.Context, interface{}) string) {
+				g.AddConditionalEdgeWithTargets("start", condition, "done", graph.END)
+			},
+		},
+		{
+			name: "unknown target",
+			declare: func(g *graph.MessageGraph, condition func(context.Context, interface{}) string) {
+				g.AddConditionalEdgeWithTargets("start", condition, "done", "missing")
+			},
+			invalid: "missing",
+		},
+		{
+			name: "unknown path",
+			declare: func(g *graph.MessageGraph, condition func(context.Context, interface{}) string) {
+				g.AddConditionalEdgeWithPathMap("start", condition, map[string]string{"yes": "done", "no": "typo"})
+			},
+			invalid: "typo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			g := graph.NewMessageGraph()
+			g.AddNode("start", func(_ context.Context, state interface{}) (interface{}, error) {
+				return state, nil
+			})
+			g.AddNode("done", func(_ context.Context, state interface{}) (interface{}, error) {
+				return state, nil
+			})
+			g.AddEdge("done", graph.END)
+			g.SetEntryPoint("start")
+			tt.declare(g, func(_ context.Context, _ interface{}) string {
+				return "done"
+			})
+
+			_, err := g.Compile()
+			if tt.invalid == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, graph.ErrNodeNotFound) || !strings.Contains(err.Error(), tt.invalid) {
+				t.Errorf("expected a missing node error for %s, got %v", tt.invalid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+// chainRecorder records the names of the chains reported to callbacks
+type chainRecorder struct {
+	graph.NoOpCallbackHandler
+	names []string
+	tags  []string
+}
+
+func (r *chainRecorder) OnChainStart(_ context.Context, serialized map[string]interface{}, _ map[string]interface{}, _ string, _ *string, tags []string, _ map[string]interface{}) {
+	r.names = append(r.names, serialized["name"].(string))
+	r.tags = tags
+}
+
+func TestConfigFromContext(t *testing.T) {
+	t.Parallel()
+
+	model := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		config := graph.ConfigFromContext(ctx)
+		if config == nil {
+			return "no config", nil
+		}
+		return config.Configurable["model"], nil
+	}
+
+	// Nodes of a subgraph see the config of the run of the parent graph
+	subgraph := graph.NewMessageGraph()
+	subgraph.AddNode("model", model)
+	subgraph.AddEdge("model", graph.END)
+	subgraph.SetEntryPoint("model")
+
+	g := graph.NewMessageGraph()
+	if err := g.AddSubgraph("agent", subgraph); err != nil {
+		t.Fatalf("failed to add subgraph: %v", err)
+	}
+	g.AddEdge("agent", graph.END)
+	g.SetEntryPoint("agent")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	config := &graph.Config{Configurable: map[string]interface{}{"model": "small"}}
+	if result, err := runnable.InvokeWithConfig(context.Background(), nil, config); err != nil || result != "small" {
+		t.Errorf("expected the configured model, got %v (%v)", result, err)
+	}
+	if result, err := runnable.Invoke(context.Background(), nil); err != nil || result != "no config" {
+		t.Errorf("expected no config, got %v (%v)", result, err)
+	}
+}
+
+func TestConfig_Timeout(t *testing.T) {
+	t.Parallel()
+
+	// The node ignores its context, so the run stops before the next step
+	g := graph.NewStateGraph()
+	g.AddNode("poll", func(_ context.Context, state interface{}) (interface{}, error) {
+		time.Sleep(5 * time.Millisecond)
+		return state, nil
+	})
+	g.AddConditionalEdge("poll", func(_ context.Context, _ interface{}) string {
+		return "poll"
+	})
+	g.SetEntryPoint("poll")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	timeout := 50 * time.Millisecond
+	_, err = runnable.InvokeWithConfig(context.Background(), map[string]interface{}{}, &graph.Config{Timeout: &timeout})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the run to time out, got %v", err)
+	}
+}
+
+func TestConfig_RunName(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewMessageGraph()
+	g.AddNode("answer", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	g.AddEdge("answer", graph.END)
+	g.SetEntryPoint("answer")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	var spans []string
+	tracer := graph.NewTracer()
+	tracer.AddHook(graph.TraceHookFunc(func(_ context.Context, span *graph.TraceSpan) {
+		if span.Event == graph.TraceEventGraphStart {
+			spans = append(spans, span.NodeName)
+		}
+	}))
+	runnable.SetTracer(tracer)
+
+	recorder := &chainRecorder{}
+	config := &graph.Config{Callbacks: []graph.CallbackHandler{recorder}, RunName: "support-bot", Tags: []string{"beta"}}
+	if _, err := runnable.InvokeWithConfig(context.Background(), "hello", config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(recorder.names) != 1 || recorder.names[0] != "support-bot" || len(recorder.tags) != 1 {
+		t.Errorf("expected the run name and tags in callbacks, got %v %v", recorder.names, recorder.tags)
+	}
+	if len(spans) == 0 || spans[0] != "support-bot" {
+		t.Errorf("expected the run name in traces, got %v", spans)
+	}
+}
@@ -0,0 +1,396 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// threadDirPrefix prefixes the names of the per-thread directories of a FileCheckpointStore
+const threadDirPrefix = "thread-"
+
+// lockFileName is the name of the lock file that serializes access across processes
+const lockFileName = ".lock"
+
+// FileCheckpointStore provides file-based checkpoint storage.
+// A store created with NewFileCheckpointStoreWithDir keeps one JSON file per checkpoint
+// under a directory and supports every CheckpointStore operation. A store created with
+// NewFileCheckpointStore writes checkpoints to a stream and only supports Save and Load.
+type FileCheckpointStore struct {
+	writer io.Writer
+	reader io.Reader
+	dir    string
+	mutex  sync.RWMutex
+
+	serializer Serializer
+}
+
+// checkpointRecord is the JSON document a FileCheckpointStore keeps for a checkpoint.
+// States the serializer encodes as JSON are kept inline; others are kept base64 encoded.
+// The states of sends and the values of pending writes are kept the same way.
+type checkpointRecord struct {
+	*Checkpoint
+	State         json.RawMessage      `json:"state,omitempty"`
+	EncodedState  []byte               `json:"encoded_state,omitempty"`
+	Sends         []sendRecord         `json:"sends,omitempty"`
+	PendingWrites []pendingWriteRecord `json:"pending_writes,omitempty"`
+}
+
+// NewFileCheckpointStore creates a new file-based checkpoint store
+func NewFileCheckpointStore(writer io.Writer, reader io.Reader) *FileCheckpointStore {
+	return &FileCheckpointStore{
+		writer: writer,
+		reader: reader,
+	}
+}
+
+// NewFileCheckpointStoreWithDir creates a file-based checkpoint store that keeps one file per
+// checkpoint under dir, in a subdirectory per thread, creating dir if needed. Files are written
+// atomically by renaming a temporary file, and a lock file serializes changes, so several
+// processes can share the directory. The lock file is locked with flock on Unix and LockFileEx
+// on Windows; on other platforms, such as Plan 9 and WebAssembly, changes are only serialized
+// within the process and the directory must not be shared.
+func NewFileCheckpointStoreWithDir(dir string) (*FileCheckpointStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+
+	return &FileCheckpointStore{
+		dir: dir,
+	}, nil
+}
+
+// SetSerializer sets the serializer used for checkpoint states. JSONSerializer is used by default.
+func (f *FileCheckpointStore) SetSerializer(serializer Serializer) {
+	f.serializer = serializer
+}
+
+// Save implements CheckpointStore interface for file storage
+func (f *FileCheckpointStore) Save(_ context.Context, checkpoint *Checkpoint) error {
+	data, err := f.marshalCheckpoint(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	if f.dir == "" {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+
+		_, err = f.writer.Write(data)
+		if err != nil {
+			return fmt.Errorf("failed to write checkpoint: %w", err)
+		}
+
+		return nil
+	}
+
+	return f.withLock(true, func() error {
+		// A checkpoint saved again under another thread must not remain in the old one
+		paths, err := f.checkpointPaths(checkpoint.ID)
+		if err != nil {
+			return err
+		}
+
+		path := f.checkpointPath(checkpointThreadID(checkpoint), checkpoint.ID)
+		for _, existing := range paths {
+			if existing != path {
+				if err := os.Remove(existing); err != nil && !errors.Is(err, os.ErrNotExist) {
+					return fmt.Errorf("failed to remove checkpoint: %w", err)
+				}
+			}
+		}
+
+		if err := writeFileAtomic(path, data); err != nil {
+			return fmt.Errorf("failed to write checkpoint: %w", err)
+		}
+		return nil
+	})
+}
+
+// Load implements CheckpointStore interface for file storage
+func (f *FileCheckpointStore) Load(_ context.Context, checkpointID string) (*Checkpoint, error) {
+	if f.dir == "" {
+		f.mutex.RLock()
+		defer f.mutex.RUnlock()
+
+		data, err := io.ReadAll(f.reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+		}
+
+		checkpoint, err := f.unmarshalCheckpoint(data)
+		if err != nil {
+			return nil, err
+		}
+
+		if checkpoint.ID != checkpointID {
+			return nil, fmt.Errorf("checkpoint not found: %s", checkpointID)
+		}
+
+		return checkpoint, nil
+	}
+
+	var checkpoint *Checkpoint
+	err := f.withLock(false, func() error {
+		paths, err := f.checkpointPaths(checkpointID)
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("checkpoint not found: %s", checkpointID)
+		}
+
+		checkpoint, err = f.readCheckpointFile(paths[0])
+		return err
+	})
+
+	return checkpoint, err
+}
+
+// List implements CheckpointStore interface for file storage.
+// Checkpoints are returned in the order they were created.
+func (f *FileCheckpointStore) List(_ context.Context, executionID string) ([]*Checkpoint, error) {
+	if f.dir == "" {
+		return nil, fmt.Errorf("list operation not supported by stream-based file store")
+	}
+
+	var checkpoints []*Checkpoint
+	err := f.withLock(false, func() error {
+		entries, err := os.ReadDir(f.threadDir(executionID))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list checkpoints: %w", err)
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+				continue
+			}
+
+			checkpoint, err := f.readCheckpointFile(filepath.Join(f.threadDir(executionID), entry.Name()))
+			if err != nil {
+				return err
+			}
+			checkpoints = append(checkpoints, checkpoint)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(checkpoints, func(i, j int) bool {
+		if checkpoints[i].Version != checkpoints[j].Version {
+			return checkpoints[i].Version < checkpoints[j].Version
+		}
+		return checkpoints[i].Timestamp.Before(checkpoints[j].Timestamp)
+	})
+
+	return checkpoints, nil
+}
+
+// Delete implements CheckpointStore interface for file storage
+func (f *FileCheckpointStore) Delete(_ context.Context, checkpointID string) error {
+	if f.dir == "" {
+		return fmt.Errorf("delete operation not supported by stream-based file store")
+	}
+
+	return f.withLock(true, func() error {
+		paths, err := f.checkpointPaths(checkpointID)
+		if err != nil {
+			return err
+		}
+
+		for _, path := range paths {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to delete checkpoint: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// Clear implements CheckpointStore interface for file storage
+func (f *FileCheckpointStore) Clear(_ context.Context, executionID string) error {
+	if f.dir == "" {
+		return fmt.Errorf("clear operation not supported by stream-based file store")
+	}
+
+	return f.withLock(true, func() error {
+		if err := os.RemoveAll(f.threadDir(executionID)); err != nil {
+			return fmt.Errorf("failed to clear checkpoints: %w", err)
+		}
+		return nil
+	})
+}
+
+// withLock runs fn while holding the store's lock, exclusively or shared with other readers.
+// The lock is held both within the process and, through the lock file, across processes.
+func (f *FileCheckpointStore) withLock(exclusive bool, fn func() error) error {
+	if exclusive {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+	} else {
+		f.mutex.RLock()
+		defer f.mutex.RUnlock()
+	}
+
+	lock, err := os.OpenFile(filepath.Join(f.dir, lockFileName), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file: %w", err)
+	}
+	defer lock.Close()
+
+	if err := lockFile(lock, exclusive); err != nil {
+		return fmt.Errorf("failed to lock checkpoint directory: %w", err)
+	}
+	defer func() {
+		_ = unlockFile(lock)
+	}()
+
+	return fn()
+}
+
+// threadDir returns the directory holding the checkpoints of a thread
+func (f *FileCheckpointStore) threadDir(threadID string) string {
+	return filepath.Join(f.dir, threadDirPrefix+url.PathEscape(threadID))
+}
+
+// checkpointPath returns the file a checkpoint of the given thread is stored in
+func (f *FileCheckpointStore) checkpointPath(threadID, checkpointID string) string {
+	return filepath.Join(f.threadDir(threadID), url.PathEscape(checkpointID)+".json")
+}
+
+// checkpointPaths returns the files holding the checkpoint with the given ID, in any thread
+func (f *FileCheckpointStore) checkpointPaths(checkpointID string) ([]string, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint directory: %w", err)
+	}
+
+	fileName := url.PathEscape(checkpointID) + ".json"
+
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), threadDirPrefix) {
+			continue
+		}
+
+		path := filepath.Join(f.dir, entry.Name(), fileName)
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths, nil
+}
+
+// readCheckpointFile reads and decodes a checkpoint file
+func (f *FileCheckpointStore) readCheckpointFile(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	checkpoint, err := f.unmarshalCheckpoint(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	return checkpoint, nil
+}
+
+// marshalCheckpoint encodes a checkpoint as a checkpointRecord
+func (f *FileCheckpointStore) marshalCheckpoint(checkpoint *Checkpoint) ([]byte, error) {
+	state, encodedState, err := encodeState(f.serializer, checkpoint.State)
+	if err != nil {
+		return nil, err
+	}
+
+	sends, err := encodeSends(f.serializer, checkpoint.Sends)
+	if err != nil {
+		return nil, err
+	}
+
+	pendingWrites, err := encodePendingWrites(f.serializer, checkpoint.PendingWrites)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(checkpointRecord{
+		Checkpoint:    checkpoint,
+		State:         state,
+		EncodedState:  encodedState,
+		Sends:         sends,
+		PendingWrites: pendingWrites,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	return data, nil
+}
+
+// unmarshalCheckpoint decodes a checkpointRecord
+func (f *FileCheckpointStore) unmarshalCheckpoint(data []byte) (*Checkpoint, error) {
+	record := checkpointRecord{Checkpoint: &Checkpoint{}}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checkpoint: %w", err)
+	}
+
+	state, err := decodeState(f.serializer, record.State, record.EncodedState)
+	if err != nil {
+		return nil, err
+	}
+	record.Checkpoint.State = state
+
+	if record.Checkpoint.Sends, err = decodeSends(f.serializer, record.Sends); err != nil {
+		return nil, err
+	}
+	if record.Checkpoint.PendingWrites, err = decodePendingWrites(f.serializer, record.PendingWrites); err != nil {
+		return nil, err
+	}
+
+	return record.Checkpoint, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place,
+// so that readers never observe a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpName, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+	}
+
+	return err
+}
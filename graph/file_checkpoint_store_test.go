@@ -0,0 +1,176 @@
+package graph_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+func TestFileCheckpointStoreWithDir_Operations(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := graph.NewFileCheckpointStoreWithDir(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	ctx := context.Background()
+
+	checkpoints := []*graph.Checkpoint{
+		{ID: "cp/1", ThreadID: "thread/a", NodeName: "first", State: "one", Version: 1, Timestamp: time.Now()},
+		{ID: "cp/2", ThreadID: "thread/a", NodeName: "second", State: "two", Version: 2, Timestamp: time.Now()},
+		{ID: "cp/3", NodeName: "other", State: "three", Version: 1, Metadata: map[string]interface{}{
+			"execution_id": "thread/b",
+		}},
+	}
+	for _, checkpoint := range checkpoints {
+		if err := store.Save(ctx, checkpoint); err != nil {
+			t.Fatalf("failed to save checkpoint: %v", err)
+		}
+	}
+
+	loaded, err := store.Load(ctx, "cp/2")
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+	if loaded.NodeName != "second" || loaded.State != "two" || loaded.ThreadID != "thread/a" {
+		t.Errorf("unexpected checkpoint: %+v", loaded)
+	}
+
+	if _, err := store.Load(ctx, "missing"); err == nil || !strings.Contains(err.Error(), "checkpoint not found") {
+		t.Errorf("expected 'checkpoint not found' error, got %v", err)
+	}
+
+	listed, err := store.List(ctx, "thread/a")
+	if err != nil {
+		t.Fatalf("failed to list checkpoints: %v", err)
+	}
+	if len(listed) != 2 || listed[0].ID != "cp/1" || listed[1].ID != "cp/2" {
+		t.Errorf("expected thread checkpoints in order, got %d", len(listed))
+	}
+
+	// Saving again replaces the checkpoint
+	checkpoints[0].State = "updated"
+	if err := store.Save(ctx, checkpoints[0]); err != nil {
+		t.Fatalf("failed to save checkpoint: %v", err)
+	}
+	if loaded, err := store.Load(ctx, "cp/1"); err != nil || loaded.State != "updated" {
+		t.Errorf("expected updated checkpoint, got %v (%v)", loaded, err)
+	}
+
+	if err := store.Delete(ctx, "cp/1"); err != nil {
+		t.Fatalf("failed to delete checkpoint: %v", err)
+	}
+	if _, err := store.Load(ctx, "cp/1"); err == nil {
+		t.Error("expected deleted checkpoint to be gone")
+	}
+
+	if err := store.Clear(ctx, "thread/a"); err != nil {
+		t.Fatalf("failed to clear checkpoints: %v", err)
+	}
+	if listed, _ := store.List(ctx, "thread/a"); len(listed) != 0 {
+		t.Errorf("expected no checkpoints after clear, got %d", len(listed))
+	}
+	if listed, _ := store.List(ctx, "thread/b"); len(listed) != 1 {
+		t.Errorf("expected other thread to be kept, got %d", len(listed))
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".tmp-") {
+			t.Errorf("unexpected temporary file %s", entry.Name())
+		}
+	}
+}
+
+func TestFileCheckpointStoreWithDir_SharedDirectory(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	// Separate store instances stand in for separate processes
+	var wg sync.WaitGroup
+	for writer := 0; writer < 4; writer++ {
+		store, err := graph.NewFileCheckpointStoreWithDir(dir)
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+
+		wg.Add(1)
+		go func(writer int, store *graph.FileCheckpointStore) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				err := store.Save(ctx, &graph.Checkpoint{
+					ID:       fmt.Sprintf("cp_%d_%d", writer, i),
+					ThreadID: "shared",
+					State:    i,
+				})
+				if err != nil {
+					t.Errorf("failed to save checkpoint: %v", err)
+				}
+			}
+		}(writer, store)
+	}
+	wg.Wait()
+
+	store, err := graph.NewFileCheckpointStoreWithDir(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	listed, err := store.List(ctx, "shared")
+	if err != nil {
+		t.Fatalf("failed to list checkpoints: %v", err)
+	}
+	if len(listed) != 40 {
+		t.Errorf("expected 40 checkpoints, got %d", len(listed))
+	}
+}
+
+func TestFileCheckpointStoreWithDir_ThreadHistory(t *testing.T) {
+	t.Parallel()
+
+	store, err := graph.NewFileCheckpointStoreWithDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	g := newReviewGraph()
+	g.SetCheckpointConfig(graph.CheckpointConfig{Store: store, AutoSave: true})
+
+	runnable, err := g.CompileCheckpointable(graph.WithInterruptBefore("publish"))
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := runnable.InvokeWithConfig(ctx, "post", threadConfig("files")); err == nil {
+		t.Fatal("expected the run to be interrupted")
+	}
+
+	result, err := runnable.InvokeWithConfig(ctx, nil, threadConfig("files"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "post draft published" {
+		t.Errorf("unexpected result: %v", result)
+	}
+
+	history, err := runnable.GetStateHistory(ctx, "files")
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	if len(history) != 3 || history[0].NodeName != "publish" {
+		t.Errorf("expected 3 checkpoints ending with publish, got %d", len(history))
+	}
+}
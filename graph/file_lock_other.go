@@ -0,0 +1,16 @@
+//go:build !unix && !windows
+
+package graph
+
+import "os"
+
+// lockFile is a no-op on platforms without file locks; access is then only serialized
+// within the process
+func lockFile(_ *os.File, _ bool) error {
+	return nil
+}
+
+// unlockFile is a no-op on platforms without file locks
+func unlockFile(_ *os.File) error {
+	return nil
+}
@@ -3,7 +3,6 @@ package graph
 import (
 	"context"
 	"errors"
-	"fmt"
 )
 
 // END is a special constant used to represent the end node in the graph.
@@ -20,16 +19,19 @@ var (
 	ErrNoOutgoingEdge = errors.New("no outgoing edge found for node")
 )
 
-// Node represents a node in the message graph.
-type Node struct {
+// TypedNode represents a node in a graph whose state has the static type S.
+type TypedNode[S any] struct {
 	// Name is the unique identifier for the node.
 	Name string
 
 	// Function is the function associated with the node.
-	// It takes a context and any state as input and returns the updated state and an error.
-	Function func(ctx context.Context, state interface{}) (interface{}, error)
+	// It takes a context and the current state as input and returns the updated state and an error.
+	Function func(ctx context.Context, state S) (S, error)
 }
 
+// Node represents a node in the message graph.
+type Node = TypedNode[interface{}]
+
 // Edge represents an edge in the message graph.
 type Edge struct {
 	// From is the name of the node from which the edge originates.
@@ -39,39 +41,63 @@ type Edge struct {
 	To string
 }
 
-// MessageGraph represents a message graph.
-type MessageGraph struct {
-	// nodes is a map of node names to their corresponding Node objects.
-	nodes map[string]Node
+// TypedMessageGraph represents a message graph whose state has the static type S.
+// Node functions and conditional edges receive S directly, so a node returning the
+// wrong state shape is a compile-time error rather than a failed type assertion.
+type TypedMessageGraph[S any] struct {
+	// nodes is a map of node names to their corresponding TypedNode objects.
+	nodes map[string]TypedNode[S]
 
 	// edges is a slice of Edge objects representing the connections between nodes.
 	edges []Edge
 
 	// conditionalEdges contains a map between "From" node, while "To" node is derived based on the condition.
-	conditionalEdges map[string]func(ctx context.Context, state interface{}) string
+	conditionalEdges map[string]func(ctx context.Context, state S) string
+
+	// sendEdges maps a node to the function that sends the next nodes their own input.
+	sendEdges map[string]func(ctx context.Context, state S) []Send
+
+	// pathMaps lists the targets of conditional edges declared with their possible targets,
+	// keyed by the value the condition returns for each.
+	pathMaps map[string]map[string]string
 
 	// entryPoint is the name of the entry point node in the graph.
 	entryPoint string
+
+	// merge combines the updates of nodes that run in the same superstep.
+	merge StateMergeFunc[S]
+
+	// retryPolicy defines retry behavior for failed nodes.
+	retryPolicy *RetryPolicy
+
+	// middleware wraps every node of the graph.
+	middleware []TypedMiddleware[S]
 }
 
-// NewMessageGraph creates a new instance of MessageGraph.
-func NewMessageGraph() *MessageGraph {
-	return &MessageGraph{
-		nodes:            make(map[string]Node),
-		conditionalEdges: make(map[string]func(ctx context.Context, state interface{}) string),
+// NewTypedMessageGraph creates a new instance of TypedMessageGraph.
+func NewTypedMessageGraph[S any]() *TypedMessageGraph[S] {
+	return &TypedMessageGraph[S]{
+		nodes:            make(map[string]TypedNode[S]),
+		conditionalEdges: make(map[string]func(ctx context.Context, state S) string),
+		sendEdges:        make(map[string]func(ctx context.Context, state S) []Send),
+		pathMaps:         make(map[string]map[string]string),
 	}
 }
 
 // AddNode adds a new node to the message graph with the given name and function.
-func (g *MessageGraph) AddNode(name string, fn func(ctx context.Context, state interface{}) (interface{}, error)) {
-	g.nodes[name] = Node{
-		Name:     name,
-		Function: fn,
-	}
+// Options such as WithMiddleware configure the node.
+func (g *TypedMessageGraph[S]) AddNode(name string, fn func(ctx context.Context, state S) (S, error), opts ...TypedNodeOption[S]) {
+	g.nodes[name] = newNode(name, fn, opts)
+}
+
+// Use adds middlewares applied to every node of the graph when it is compiled. They wrap
+// the middlewares of each node, the first being the outermost.
+func (g *TypedMessageGraph[S]) Use(middleware ...TypedMiddleware[S]) {
+	g.middleware = append(g.middleware, middleware...)
 }
 
 // AddEdge adds a new edge to the message graph between the "from" and "to" nodes.
-func (g *MessageGraph) AddEdge(from, to string) {
+func (g *TypedMessageGraph[S]) AddEdge(from, to string) {
 	g.edges = append(g.edges, Edge{
 		From: from,
 		To:   to,
@@ -80,190 +106,184 @@ func (g *MessageGraph) AddEdge(from, to string) {
 
 // AddConditionalEdge adds a conditional edge where the target node is determined at runtime.
 // The condition function receives the current state and returns the name of the next node.
-func (g *MessageGraph) AddConditionalEdge(from string, condition func(ctx context.Context, state interface{}) string) {
+func (g *TypedMessageGraph[S]) AddConditionalEdge(from string, condition func(ctx context.Context, state S) string) {
 	g.conditionalEdges[from] = condition
+	delete(g.pathMaps, from)
+}
+
+// AddConditionalEdgeWithPathMap adds a conditional edge whose condition returns a key of the
+// path map, which names the node to route to. Compile checks that every node of the path map
+// exists, and the graph's visualizations draw each path as an edge labeled with its key.
+func (g *TypedMessageGraph[S]) AddConditionalEdgeWithPathMap(from string, condition func(ctx context.Context, state S) string, pathMap map[string]string) {
+	g.conditionalEdges[from] = condition
+	g.pathMaps[from] = copyPathMap(pathMap)
+}
+
+// AddConditionalEdgeWithTargets adds a conditional edge whose condition returns one of the
+// given nodes. Compile checks that every target exists, and the graph's visualizations draw
+// an edge to each of them.
+func (g *TypedMessageGraph[S]) AddConditionalEdgeWithTargets(from string, condition func(ctx context.Context, state S) string, targets ...string) {
+	g.AddConditionalEdgeWithPathMap(from, condition, targetPathMap(targets))
+}
+
+// AddSendEdge adds a conditional edge that fans out over data found at runtime.
+// The function receives the current state and returns the nodes to run next, each with its
+// own input state; the same node can be sent several inputs. Their updates are merged like
+// those of other nodes of a step, and Config.MaxConcurrency bounds how many run at once.
+func (g *TypedMessageGraph[S]) AddSendEdge(from string, send func(ctx context.Context, state S) []Send) {
+	g.sendEdges[from] = send
 }
 
 // SetEntryPoint sets the entry point node name for the message graph.
-func (g *MessageGraph) SetEntryPoint(name string) {
+func (g *TypedMessageGraph[S]) SetEntryPoint(name string) {
 	g.entryPoint = name
 }
 
-// Runnable represents a compiled message graph that can be invoked.
-type Runnable struct {
-	// graph is the underlying MessageGraph object.
-	graph *MessageGraph
+// SetRetryPolicy sets the retry policy applied to every node of the graph.
+func (g *TypedMessageGraph[S]) SetRetryPolicy(policy *RetryPolicy) {
+	g.retryPolicy = policy
+}
+
+// SetStateMerger sets the function used to combine the states returned by nodes
+// that run concurrently in the same step, e.g. after AddEdge("a", "b") and AddEdge("a", "c").
+func (g *TypedMessageGraph[S]) SetStateMerger(merge StateMergeFunc[S]) {
+	g.merge = merge
+}
+
+// TypedRunnable represents a compiled TypedMessageGraph that can be invoked.
+type TypedRunnable[S any] struct {
+	// graph is the underlying TypedMessageGraph object.
+	graph *TypedMessageGraph[S]
+	// nodes are the nodes of the graph wrapped by its middlewares
+	nodes map[string]TypedNode[S]
 	// tracer is the optional tracer for observability
 	tracer *Tracer
+	// options holds the settings given to Compile
+	options compileOptions
 }
 
-// Compile compiles the message graph and returns a Runnable instance.
-// It returns an error if the entry point is not set.
-func (g *MessageGraph) Compile() (*Runnable, error) {
-	if g.entryPoint == "" {
-		return nil, ErrEntryPointNotSet
+// Validate checks the structure of the graph. It returns a *ValidationError listing every
+// problem found, including the warnings that don't prevent the graph from compiling: nodes
+// without outgoing edges, unreachable nodes and cycles that never reach END, which are
+// fine when the nodes route with a Command.
+func (g *TypedMessageGraph[S]) Validate() error {
+	if problems := g.topology().validate(); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func (g *TypedMessageGraph[S]) topology() topology {
+	return newTopology(g.entryPoint, g.nodes, g.edges, g.conditionalEdges, g.sendEdges, g.pathMaps)
+}
+
+func (g *TypedMessageGraph[S]) structure() graphStructure[S] {
+	return graphStructure[S]{
+		edges:            g.edges,
+		conditionalEdges: g.conditionalEdges,
+		sendEdges:        g.sendEdges,
+		pathMaps:         g.pathMaps,
+		entryPoint:       g.entryPoint,
+		merge:            g.merge,
+		retryPolicy:      g.retryPolicy,
+	}
+}
+
+// Compile compiles the message graph and returns a TypedRunnable instance.
+// It returns a *ValidationError if the structure of the graph is invalid (see Validate), or an
+// error if an option refers to an unknown node.
+func (g *TypedMessageGraph[S]) Compile(opts ...CompileOption) (*TypedRunnable[S], error) {
+	if err := g.topology().check(); err != nil {
+		return nil, err
 	}
 
-	return &Runnable{
-		graph:  g,
-		tracer: nil, // Initialize with no tracer
+	options, err := newCompileOptions(g.nodes, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TypedRunnable[S]{
+		graph:   g,
+		nodes:   withMiddleware(g.nodes, g.middleware),
+		tracer:  nil, // Initialize with no tracer
+		options: options,
 	}, nil
 }
 
 // SetTracer sets a tracer for observability
-func (r *Runnable) SetTracer(tracer *Tracer) {
+func (r *TypedRunnable[S]) SetTracer(tracer *Tracer) {
 	r.tracer = tracer
 }
 
-// WithTracer returns a new Runnable with the given tracer
-func (r *Runnable) WithTracer(tracer *Tracer) *Runnable {
-	return &Runnable{
-		graph:  r.graph,
-		tracer: tracer,
+// WithTracer returns a new TypedRunnable with the given tracer
+func (r *TypedRunnable[S]) WithTracer(tracer *Tracer) *TypedRunnable[S] {
+	return &TypedRunnable[S]{
+		graph:   r.graph,
+		nodes:   r.nodes,
+		tracer:  tracer,
+		options: r.options,
 	}
 }
 
 // Invoke executes the compiled message graph with the given input state.
 // It returns the resulting state and an error if any occurs during the execution.
-func (r *Runnable) Invoke(ctx context.Context, initialState interface{}) (interface{}, error) {
+func (r *TypedRunnable[S]) Invoke(ctx context.Context, initialState S) (S, error) {
 	return r.InvokeWithConfig(ctx, initialState, nil)
 }
 
 // InvokeWithConfig executes the compiled message graph with the given input state and config.
+// Nodes triggered by the same step run concurrently and their updates are merged before the next step.
 // It returns the resulting state and an error if any occurs during the execution.
-func (r *Runnable) InvokeWithConfig(ctx context.Context, initialState interface{}, config *Config) (interface{}, error) {
-	state := initialState
-	currentNode := r.graph.entryPoint
-
-	// Generate run ID for callbacks
-	runID := generateRunID()
-
-	// Notify callbacks of graph start
-	if config != nil && len(config.Callbacks) > 0 {
-		serialized := map[string]interface{}{
-			"name": "graph",
-			"type": "chain",
-		}
-		inputs := convertStateToMap(initialState)
-
-		for _, cb := range config.Callbacks {
-			cb.OnChainStart(ctx, serialized, inputs, runID, nil, config.Tags, config.Metadata)
-		}
+// When a breakpoint is hit, the state reached so far is returned with a *GraphInterrupt.
+func (r *TypedRunnable[S]) InvokeWithConfig(ctx context.Context, initialState S, config *Config) (S, error) {
+	ctx, cancel := withConfig(ctx, config)
+	defer cancel()
+
+	state, err := r.newEngine(config).start(ctx, initialState, config)
+	if err != nil && !errors.Is(err, ErrGraphInterrupted) {
+		var zero S
+		return zero, err
 	}
+	return state, err
+}
 
-	// Start graph tracing if tracer is set
-	var graphSpan *TraceSpan
-	if r.tracer != nil {
-		graphSpan = r.tracer.StartSpan(ctx, TraceEventGraphStart, "graph")
-		graphSpan.State = initialState
-	}
+// newEngine creates the superstep engine for one invocation, with hooks for the runnable's
+// tracer, the graph's retry policy and the callbacks of the given config.
+func (r *TypedRunnable[S]) newEngine(config *Config) *superstepEngine[S] {
+	return newSuperstepEngine(r.graph.structure(), r.nodes, r.tracer, r.options, config)
+}
 
-	for {
-		if currentNode == END {
-			break
-		}
-
-		node, ok := r.graph.nodes[currentNode]
-		if !ok {
-			return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, currentNode)
-		}
-
-		// Start node tracing
-		var nodeSpan *TraceSpan
-		if r.tracer != nil {
-			nodeSpan = r.tracer.StartSpan(ctx, TraceEventNodeStart, currentNode)
-			nodeSpan.State = state
-		}
-
-		var err error
-		state, err = node.Function(ctx, state)
-
-		// End node tracing
-		if r.tracer != nil && nodeSpan != nil {
-			if err != nil {
-				r.tracer.EndSpan(ctx, nodeSpan, state, err)
-				// Also emit error event
-				errorSpan := r.tracer.StartSpan(ctx, TraceEventNodeError, currentNode)
-				errorSpan.Error = err
-				errorSpan.State = state
-				r.tracer.EndSpan(ctx, errorSpan, state, err)
-			} else {
-				r.tracer.EndSpan(ctx, nodeSpan, state, nil)
-			}
-		}
-
-		if err != nil {
-			// Notify callbacks of error
-			if config != nil && len(config.Callbacks) > 0 {
-				for _, cb := range config.Callbacks {
-					cb.OnChainError(ctx, err, runID)
-				}
-			}
-			return nil, fmt.Errorf("error in node %s: %w", currentNode, err)
-		}
-
-		// Notify callbacks of node execution (as tool)
-		if config != nil && len(config.Callbacks) > 0 {
-			nodeRunID := generateRunID()
-			serialized := map[string]interface{}{
-				"name": currentNode,
-				"type": "tool",
-			}
-			for _, cb := range config.Callbacks {
-				cb.OnToolStart(ctx, serialized, convertStateToString(state), nodeRunID, &runID, config.Tags, config.Metadata)
-				cb.OnToolEnd(ctx, convertStateToString(state), nodeRunID)
-			}
-		}
-
-		// Determine next node
-		var nextNode string
-
-		// First check for conditional edges
-		nextNodeFn, hasConditional := r.graph.conditionalEdges[currentNode]
-		if hasConditional {
-			nextNode = nextNodeFn(ctx, state)
-			if nextNode == "" {
-				return nil, fmt.Errorf("conditional edge returned empty next node from %s", currentNode)
-			}
-		} else {
-			// Then check regular edges
-			foundNext := false
-			for _, edge := range r.graph.edges {
-				if edge.From == currentNode {
-					nextNode = edge.To
-					foundNext = true
-					break
-				}
-			}
-
-			if !foundNext {
-				return nil, fmt.Errorf("%w: %s", ErrNoOutgoingEdge, currentNode)
-			}
-		}
-
-		// Trace edge traversal
-		if r.tracer != nil && nextNode != "" && nextNode != END {
-			edgeSpan := r.tracer.StartSpan(ctx, TraceEventEdgeTraversal, fmt.Sprintf("%s->%s", currentNode, nextNode))
-			edgeSpan.FromNode = currentNode
-			edgeSpan.ToNode = nextNode
-			r.tracer.EndSpan(ctx, edgeSpan, state, nil)
-		}
-
-		currentNode = nextNode
-	}
+// MessageGraph represents a message graph with untyped state.
+// It is a thin wrapper over TypedMessageGraph[interface{}].
+type MessageGraph struct {
+	*TypedMessageGraph[interface{}]
+}
 
-	// End graph tracing
-	if r.tracer != nil && graphSpan != nil {
-		r.tracer.EndSpan(ctx, graphSpan, state, nil)
+// NewMessageGraph creates a new instance of MessageGraph.
+func NewMessageGraph() *MessageGraph {
+	return &MessageGraph{
+		TypedMessageGraph: NewTypedMessageGraph[interface{}](),
 	}
+}
 
-	// Notify callbacks of graph end
-	if config != nil && len(config.Callbacks) > 0 {
-		outputs := convertStateToMap(state)
-		for _, cb := range config.Callbacks {
-			cb.OnChainEnd(ctx, outputs, runID)
-		}
+// Runnable represents a compiled message graph that can be invoked.
+type Runnable struct {
+	*TypedRunnable[interface{}]
+}
+
+// Compile compiles the message graph and returns a Runnable instance.
+// It returns a *ValidationError if the structure of the graph is invalid, or an error if an
+// option refers to an unknown node.
+func (g *MessageGraph) Compile(opts ...CompileOption) (*Runnable, error) {
+	runnable, err := g.TypedMessageGraph.Compile(opts...)
+	if err != nil {
+		return nil, err
 	}
 
-	return state, nil
+	return &Runnable{TypedRunnable: runnable}, nil
+}
+
+// WithTracer returns a new Runnable with the given tracer
+func (r *Runnable) WithTracer(tracer *Tracer) *Runnable {
+	return &Runnable{TypedRunnable: r.TypedRunnable.WithTracer(tracer)}
 }
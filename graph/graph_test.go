@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"reflect"
+	"slices"
 	"testing"
 
 	"github.com/paulnegz/langgraphgo/graph"
@@ -66,11 +68,12 @@ func ExampleMessageGraph() {
 func TestMessageGraph(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
-		name           string
-		buildGraph     func() *graph.MessageGraph
-		inputMessages  []llms.MessageContent
-		expectedOutput []llms.MessageContent
-		expectedError  error
+		name            string
+		buildGraph      func() *graph.MessageGraph
+		inputMessages   []llms.MessageContent
+		expectedOutput  []llms.MessageContent
+		expectedError   error
+		expectedProblem *graph.ValidationProblem
 	}{
 		{
 			name: "Simple graph",
@@ -119,7 +122,8 @@ func TestMessageGraph(t *testing.T) {
 				g.SetEntryPoint("node1")
 				return g
 			},
-			expectedError: fmt.Errorf("%w: node2", graph.ErrNodeNotFound),
+			expectedError:   graph.ErrNodeNotFound,
+			expectedProblem: &graph.ValidationProblem{Err: graph.ErrNodeNotFound, Nodes: []string{"node2"}, Detail: "edge from node1"},
 		},
 		{
 			name: "No outgoing edge",
@@ -157,6 +161,14 @@ func TestMessageGraph(t *testing.T) {
 				if tc.expectedError == nil || !errors.Is(err, tc.expectedError) {
 					t.Fatalf("unexpected compile error: %v", err)
 				}
+				if tc.expectedProblem != nil {
+					var validationErr *graph.ValidationError
+					if !errors.As(err, &validationErr) || !slices.ContainsFunc(validationErr.Problems, func(problem graph.ValidationProblem) bool {
+						return reflect.DeepEqual(problem, *tc.expectedProblem)
+					}) {
+						t.Fatalf("expected problem %v, got %v", tc.expectedProblem, err)
+					}
+				}
 				return
 			}
 
@@ -187,3 +199,77 @@ func TestMessageGraph(t *testing.T) {
 		})
 	}
 }
+
+type counterState struct {
+	Count int
+	Trail []string
+}
+
+func TestTypedMessageGraph(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewTypedMessageGraph[counterState]()
+	g.AddNode("increment", func(_ context.Context, state counterState) (counterState, error) {
+		state.Count++
+		state.Trail = append(state.Trail, "increment")
+		return state, nil
+	})
+	g.AddNode("double", func(_ context.Context, state counterState) (counterState, error) {
+		state.Count *= 2
+		state.Trail = append(state.Trail, "double")
+		return state, nil
+	})
+	g.AddConditionalEdge("increment", func(_ context.Context, state counterState) string {
+		if state.Count < 3 {
+			return "increment"
+		}
+		return "double"
+	})
+	g.AddEdge("double", graph.END)
+	g.SetEntryPoint("increment")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), counterState{})
+	if err != nil {
+		t.Fatalf("unexpected invoke error: %v", err)
+	}
+
+	if result.Count != 6 {
+		t.Errorf("expected count 6, got %d", result.Count)
+	}
+
+	expectedTrail := []string{"increment", "increment", "increment", "double"}
+	if fmt.Sprint(result.Trail) != fmt.Sprint(expectedTrail) {
+		t.Errorf("expected trail %v, got %v", expectedTrail, result.Trail)
+	}
+}
+
+func TestTypedMessageGraph_ErrorReturnsZeroState(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewTypedMessageGraph[counterState]()
+	g.AddNode("fail", func(_ context.Context, state counterState) (counterState, error) {
+		state.Count = 42
+		return state, errors.New("boom")
+	})
+	g.AddEdge("fail", graph.END)
+	g.SetEntryPoint("fail")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), counterState{Count: 1})
+	if err == nil || err.Error() != "error in node fail: boom" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Count != 0 || result.Trail != nil {
+		t.Errorf("expected zero state on error, got %+v", result)
+	}
+}
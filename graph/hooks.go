@@ -0,0 +1,204 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// nodeRunner executes one node of a run
+type nodeRunner[S any] func(ctx context.Context, node TypedNode[S], state S) (S, error)
+
+// runHook plugs a feature, such as tracing, callbacks, listeners, retries or checkpointing,
+// into the runs of the superstep engine. Every runnable builds its engine from the hooks of
+// the features it has, so that they work the same way in every combination.
+type runHook[S any] interface {
+	// startRun is called before the first step, and returns the context of the run
+	startRun(ctx context.Context, input S) context.Context
+
+	// endRun is called with the context of the run once it completes, fails or is interrupted
+	endRun(ctx context.Context, state S, err error)
+
+	// wrapNode wraps the execution of every node of the run
+	wrapNode(next nodeRunner[S]) nodeRunner[S]
+
+	// traverse is called for every edge followed by the run, including edges to END
+	traverse(ctx context.Context, from, to string, state S)
+
+	// endStep is called after every completed step with the nodes that ran in it, the
+	// merged state, and the nodes and sends of the next step. An error stops the run.
+	endStep(ctx context.Context, nodes []string, state S, next []string, sends []Send) error
+}
+
+// baseHook implements runHook with no effect, for hooks to override what they need
+type baseHook[S any] struct{}
+
+func (baseHook[S]) startRun(ctx context.Context, _ S) context.Context { return ctx }
+
+func (baseHook[S]) endRun(context.Context, S, error) {}
+
+func (baseHook[S]) wrapNode(next nodeRunner[S]) nodeRunner[S] { return next }
+
+func (baseHook[S]) traverse(context.Context, string, string, S) {}
+
+func (baseHook[S]) endStep(context.Context, []string, S, []string, []Send) error { return nil }
+
+// runHooks returns the hooks every runnable supports: tracing, the callbacks of the config
+// and retries. The tracer and the retry policy may be nil.
+func runHooks[S any](config *Config, tracer *Tracer, retryPolicy *RetryPolicy) []runHook[S] {
+	var hooks []runHook[S]
+	if tracer != nil {
+		hooks = append(hooks, tracingHook[S]{tracer: tracer, config: config})
+	}
+	if config != nil && len(config.Callbacks) > 0 {
+		hooks = append(hooks, callbackHook[S]{config: config, runID: generateRunID()})
+	}
+	if retryPolicy != nil {
+		hooks = append(hooks, retryHook[S]{policy: retryPolicy})
+	}
+	return hooks
+}
+
+// tracingHook records a span for the run, one for each node, nested under the span of
+// the run, and one for each edge traversal
+type tracingHook[S any] struct {
+	baseHook[S]
+	tracer *Tracer
+	config *Config
+}
+
+func (h tracingHook[S]) startRun(ctx context.Context, input S) context.Context {
+	name := ""
+	if h.config != nil {
+		name = h.config.RunName
+	}
+
+	span := h.tracer.StartSpan(ctx, TraceEventGraphStart, name)
+	span.State = input
+	if h.config != nil && len(h.config.Tags) > 0 {
+		span.Metadata["tags"] = h.config.Tags
+	}
+	return ContextWithSpan(ctx, span)
+}
+
+func (h tracingHook[S]) endRun(ctx context.Context, state S, err error) {
+	if span := SpanFromContext(ctx); span != nil {
+		h.tracer.EndSpan(ctx, span, state, err)
+	}
+}
+
+func (h tracingHook[S]) wrapNode(next nodeRunner[S]) nodeRunner[S] {
+	return func(ctx context.Context, node TypedNode[S], state S) (S, error) {
+		span := h.tracer.StartSpan(ctx, TraceEventNodeStart, node.Name)
+		span.State = state
+		ctx = ContextWithSpan(ctx, span)
+
+		result, err := next(ctx, node, state)
+		h.tracer.EndSpan(ctx, span, result, err)
+		return result, err
+	}
+}
+
+func (h tracingHook[S]) traverse(ctx context.Context, from, to string, _ S) {
+	h.tracer.TraceEdgeTraversal(ctx, from, to)
+}
+
+// callbackHook reports the run to the callbacks of its config as a chain, and every node
+// as a tool of that chain
+type callbackHook[S any] struct {
+	baseHook[S]
+	config *Config
+	runID  string
+}
+
+func (h callbackHook[S]) startRun(ctx context.Context, input S) context.Context {
+	serialized := map[string]interface{}{
+		"name": h.config.runName(),
+		"type": "chain",
+	}
+	inputs := convertStateToMap(input)
+	for _, cb := range h.config.Callbacks {
+		cb.OnChainStart(ctx, serialized, inputs, h.runID, nil, h.config.Tags, h.config.Metadata)
+	}
+	return ctx
+}
+
+func (h callbackHook[S]) endRun(ctx context.Context, state S, err error) {
+	// A run paused at a breakpoint ends with the state reached so far
+	if err != nil && !errors.Is(err, ErrGraphInterrupted) {
+		for _, cb := range h.config.Callbacks {
+			cb.OnChainError(ctx, err, h.runID)
+		}
+		return
+	}
+
+	outputs := convertStateToMap(state)
+	for _, cb := range h.config.Callbacks {
+		cb.OnChainEnd(ctx, outputs, h.runID)
+	}
+}
+
+func (h callbackHook[S]) wrapNode(next nodeRunner[S]) nodeRunner[S] {
+	return func(ctx context.Context, node TypedNode[S], state S) (S, error) {
+		nodeRunID := generateRunID()
+		serialized := map[string]interface{}{
+			"name": node.Name,
+			"type": "tool",
+		}
+		for _, cb := range h.config.Callbacks {
+			cb.OnToolStart(ctx, serialized, convertStateToString(state), nodeRunID, &h.runID, h.config.Tags, h.config.Metadata)
+		}
+
+		result, err := next(ctx, node, state)
+		for _, cb := range h.config.Callbacks {
+			if err != nil {
+				cb.OnToolError(ctx, err, nodeRunID)
+			} else {
+				cb.OnToolEnd(ctx, convertStateToString(result), nodeRunID)
+			}
+		}
+		return result, err
+	}
+}
+
+// retryHook runs a node again when it fails with an error the retry policy allows
+type retryHook[S any] struct {
+	baseHook[S]
+	policy *RetryPolicy
+}
+
+func (h retryHook[S]) wrapNode(next nodeRunner[S]) nodeRunner[S] {
+	return func(ctx context.Context, node TypedNode[S], state S) (S, error) {
+		for attempt := 0; ; attempt++ {
+			result, err := next(ctx, node, state)
+			if err == nil || attempt >= h.policy.MaxRetries || !h.policy.retryable(err) {
+				return result, err
+			}
+
+			select {
+			case <-time.After(h.policy.backoff(attempt)):
+			case <-ctx.Done():
+				var zero S
+				return zero, ctx.Err()
+			}
+		}
+	}
+}
+
+// listenerHook notifies the listeners of the nodes of a ListenableMessageGraph
+type listenerHook struct {
+	baseHook[interface{}]
+	nodes map[string]*ListenableNode
+}
+
+func (h listenerHook) wrapNode(next nodeRunner[interface{}]) nodeRunner[interface{}] {
+	return func(ctx context.Context, node Node, state interface{}) (interface{}, error) {
+		listenableNode, ok := h.nodes[node.Name]
+		if !ok {
+			return next(ctx, node, state)
+		}
+		return listenableNode.execute(ctx, state, func(ctx context.Context, state interface{}) (interface{}, error) {
+			return next(ctx, node, state)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+// toolRecorder counts the chains and tools reported to callbacks
+type toolRecorder struct {
+	graph.NoOpCallbackHandler
+	mutex  sync.Mutex
+	tools  []string
+	ended  int
+	failed int
+}
+
+func (r *toolRecorder) OnToolStart(_ context.Context, serialized map[string]interface{}, _ string, _ string, _ *string, _ []string, _ map[string]interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.tools = append(r.tools, serialized["name"].(string))
+}
+
+func (r *toolRecorder) OnChainEnd(context.Context, map[string]interface{}, string) {
+	r.ended++
+}
+
+func (r *toolRecorder) OnChainError(context.Context, error, string) {
+	r.failed++
+}
+
+func TestListenableRunnable_AllFeatures(t *testing.T) {
+	t.Parallel()
+
+	attempts := 0
+	g := graph.NewListenableMessageGraph()
+	g.AddNode("route", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	flaky := g.AddNode("flaky", func(_ context.Context, state interface{}) (interface{}, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, errors.New("flaky service")
+		}
+		return state.(string) + ">flaky", nil
+	})
+	g.AddConditionalEdgeWithTargets("route", func(_ context.Context, _ interface{}) string {
+		return "flaky"
+	}, "flaky", graph.END)
+	g.AddEdge("flaky", graph.END)
+	g.SetEntryPoint("route")
+	g.SetRetryPolicy(&graph.RetryPolicy{MaxRetries: 1, BackoffStrategy: graph.FixedBackoff, RetryableErrors: []string{"flaky"}})
+
+	var events []graph.NodeEvent
+	flaky.AddListener(graph.NodeListenerFunc(func(_ context.Context, event graph.NodeEvent, _ string, _ interface{}, _ error) {
+		events = append(events, event)
+	}))
+
+	runnable, err := g.CompileListenable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	var spans []string
+	tracer := graph.NewTracer()
+	tracer.AddHook(graph.TraceHookFunc(func(_ context.Context, span *graph.TraceSpan) {
+		if span.Event == graph.TraceEventNodeEnd {
+			spans = append(spans, span.NodeName)
+		}
+	}))
+	runnable.SetTracer(tracer)
+
+	recorder := &toolRecorder{}
+	result, err := runnable.InvokeWithConfig(context.Background(), "input", &graph.Config{Callbacks: []graph.CallbackHandler{recorder}})
+	if err != nil || result != "input>flaky" {
+		t.Fatalf("expected the conditional edge to route to flaky, got %v (%v)", result, err)
+	}
+
+	// The listener and the tracer see one execution of the node, retried within it
+	if attempts != 2 {
+		t.Errorf("expected flaky to be retried once, ran %d times", attempts)
+	}
+	if len(events) != 2 || events[0] != graph.NodeEventStart || events[1] != graph.NodeEventComplete {
+		t.Errorf("expected start and complete events, got %v", events)
+	}
+	if len(spans) != 2 || spans[0] != "route" || spans[1] != "flaky" {
+		t.Errorf("expected a span for each node, got %v", spans)
+	}
+	if len(recorder.tools) != 2 || recorder.ended != 1 || recorder.failed != 0 {
+		t.Errorf("expected callbacks for both nodes and the run, got %v, %d ended, %d failed", recorder.tools, recorder.ended, recorder.failed)
+	}
+}
+
+func TestTracedRunnable_ConditionalEdges(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewMessageGraph()
+	g.AddNode("check", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	g.AddNode("small", func(_ context.Context, state interface{}) (interface{}, error) {
+		return "small", nil
+	})
+	g.AddNode("large", func(_ context.Context, state interface{}) (interface{}, error) {
+		return "large", nil
+	})
+	g.AddConditionalEdge("check", func(_ context.Context, state interface{}) string {
+		if state.(int) > 10 {
+			return "large"
+		}
+		return "small"
+	})
+	g.AddEdge("small", graph.END)
+	g.AddEdge("large", graph.END)
+	g.SetEntryPoint("check")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	tracer := graph.NewTracer()
+	var edges []string
+	tracer.AddHook(graph.TraceHookFunc(func(_ context.Context, span *graph.TraceSpan) {
+		if span.Event == graph.TraceEventEdgeTraversal {
+			edges = append(edges, span.FromNode+">"+span.ToNode)
+		}
+	}))
+
+	result, err := graph.NewTracedRunnable(runnable, tracer).Invoke(context.Background(), 42)
+	if err != nil || result != "large" {
+		t.Fatalf("expected the conditional edge to be followed, got %v (%v)", result, err)
+	}
+	if len(edges) != 2 || edges[0] != "check>large" || edges[1] != "large>END" {
+		t.Errorf("unexpected edge traversals: %v", edges)
+	}
+}
@@ -0,0 +1,126 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrGraphInterrupted matches every *GraphInterrupt with errors.Is
+var ErrGraphInterrupted = errors.New("graph execution interrupted")
+
+// GraphInterrupt is returned when a run pauses at a breakpoint set with WithInterruptBefore,
+// WithInterruptAfter or the interrupt fields of Config, or because a node called Interrupt.
+// The runnable returns the state reached so far together with this error, so the caller
+// can review or edit it and resume.
+type GraphInterrupt struct {
+	// Node is the node the run paused at
+	Node string
+
+	// Before is true when Node runs again on resume, i.e. when the run paused before
+	// Node executed or because Node called Interrupt; it is false when the run paused after
+	Before bool
+
+	// Value is the payload a node passed to Interrupt
+	Value interface{}
+
+	// State is the state at the time of the pause
+	State interface{}
+
+	// Next lists the nodes that run when execution resumes
+	Next []string
+
+	// Sends lists the nodes that run with their own input when execution resumes
+	Sends []Send
+
+	// CheckpointID identifies the checkpoint the pause was saved to, if it was persisted
+	CheckpointID string
+
+	// dynamic is set when a node called Interrupt
+	dynamic bool
+
+	// taskID identifies the task that called Interrupt, telling sends to Node apart
+	taskID string
+
+	// resumeValues are the values the interrupted task received before calling Interrupt
+	resumeValues []interface{}
+
+	// writes are the outputs of the tasks that completed in the interrupted step
+	writes []PendingWrite
+}
+
+// Error implements the error interface
+func (e *GraphInterrupt) Error() string {
+	if e.dynamic {
+		return fmt.Sprintf("graph interrupted in node %s: %v", e.Node, e.Value)
+	}
+	if e.Before {
+		return fmt.Sprintf("graph interrupted before node %s", e.Node)
+	}
+	return fmt.Sprintf("graph interrupted after node %s", e.Node)
+}
+
+// Is reports whether the target is ErrGraphInterrupted
+func (e *GraphInterrupt) Is(target error) bool {
+	return target == ErrGraphInterrupted
+}
+
+// Interrupt pauses the run from inside a node, e.g. to ask a person to approve a tool call.
+// The first time it is called it returns a *GraphInterrupt carrying the given value, which
+// the node must return as its error. The run is then suspended and the caller receives the
+// value in GraphInterrupt.Value. When the run is resumed with a Resume command the node is
+// executed again from the start, and this time Interrupt returns the resume value instead.
+// A node may call Interrupt several times; resume values are matched to calls in order.
+//
+//	approval, err := graph.Interrupt(ctx, "delete all records?")
+//	if err != nil {
+//		return nil, err
+//	}
+func Interrupt(ctx context.Context, value interface{}) (interface{}, error) {
+	if scratch, ok := ctx.Value(interruptScratchKey{}).(*interruptScratch); ok {
+		if resumeValue, ok := scratch.next(); ok {
+			return resumeValue, nil
+		}
+	}
+
+	return nil, &GraphInterrupt{
+		Value:   value,
+		dynamic: true,
+	}
+}
+
+// interruptScratchKey is the context key for the resume values of a node execution
+type interruptScratchKey struct{}
+
+// interruptScratch hands out the resume values of a node execution to its Interrupt calls
+type interruptScratch struct {
+	values []interface{}
+	index  int
+	mutex  sync.Mutex
+}
+
+// next returns the resume value for the next Interrupt call, if there is one
+func (s *interruptScratch) next() (interface{}, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.index >= len(s.values) {
+		return nil, false
+	}
+
+	value := s.values[s.index]
+	s.index++
+	return value, true
+}
+
+// withInterruptScratch returns a context through which Interrupt receives the given resume
+// values. Without resume values, the scratch of an enclosing node execution is kept, so
+// that Interrupt calls in a graph running as a node, such as a Subgraph, receive the values
+// the outer run was resumed with.
+func withInterruptScratch(ctx context.Context, values []interface{}) context.Context {
+	if _, ok := ctx.Value(interruptScratchKey{}).(*interruptScratch); ok && len(values) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, interruptScratchKey{}, &interruptScratch{values: values})
+}
@@ -0,0 +1,317 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+func newReviewGraph() *graph.CheckpointableMessageGraph {
+	g := graph.NewCheckpointableMessageGraph()
+
+	g.AddNode("draft", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state.(string) + " draft", nil
+	})
+	g.AddNode("publish", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state.(string) + " published", nil
+	})
+	g.AddEdge("draft", "publish")
+	g.AddEdge("publish", graph.END)
+	g.SetEntryPoint("draft")
+
+	return g
+}
+
+func TestInterruptBefore_ResumeWithState(t *testing.T) {
+	t.Parallel()
+
+	runnable, err := newReviewGraph().CompileCheckpointable(graph.WithInterruptBefore("publish"))
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	state, err := runnable.Invoke(ctx, "post")
+
+	var interrupt *graph.GraphInterrupt
+	if !errors.As(err, &interrupt) {
+		t.Fatalf("expected GraphInterrupt, got %v", err)
+	}
+	if !interrupt.Before || interrupt.Node != "publish" {
+		t.Errorf("expected interrupt before publish, got %+v", interrupt)
+	}
+	if state != "post draft" || interrupt.State != "post draft" {
+		t.Errorf("expected state after draft, got %v", state)
+	}
+	if fmt.Sprint(interrupt.Next) != "[publish]" {
+		t.Errorf("expected next [publish], got %v", interrupt.Next)
+	}
+	if interrupt.CheckpointID == "" {
+		t.Fatal("expected the interrupt to be saved as a checkpoint")
+	}
+
+	result, err := runnable.ResumeWithState(ctx, interrupt.CheckpointID, "reviewed post")
+	if err != nil {
+		t.Fatalf("unexpected resume error: %v", err)
+	}
+	if result != "reviewed post published" {
+		t.Errorf("expected edited state to be published, got %v", result)
+	}
+
+	// The saved pause can also be resumed as it was
+	result, err = runnable.ResumeFromCheckpoint(ctx, interrupt.CheckpointID)
+	if err != nil {
+		t.Fatalf("unexpected resume error: %v", err)
+	}
+	if result != "post draft published" {
+		t.Errorf("expected saved state to be published, got %v", result)
+	}
+}
+
+func TestInterruptAfter_PerInvocation(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewMessageGraph()
+	g.AddNode("first", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state.(int) + 1, nil
+	})
+	g.AddNode("second", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state.(int) * 10, nil
+	})
+	g.AddEdge("first", "second")
+	g.AddEdge("second", graph.END)
+	g.SetEntryPoint("first")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	state, err := runnable.InvokeWithConfig(context.Background(), 1, &graph.Config{
+		InterruptAfter: []string{"first"},
+	})
+	if !errors.Is(err, graph.ErrGraphInterrupted) {
+		t.Fatalf("expected interrupt, got %v", err)
+	}
+	if state != 2 {
+		t.Errorf("expected state after first node, got %v", state)
+	}
+
+	// Without the config the graph runs to completion
+	result, err := runnable.Invoke(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 20 {
+		t.Errorf("expected 20, got %v", result)
+	}
+}
+
+func TestInterrupt_ResumeRequiresPendingNodes(t *testing.T) {
+	t.Parallel()
+
+	runnable, err := newReviewGraph().CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := runnable.SaveCheckpoint(ctx, "publish", "done"); err != nil {
+		t.Fatalf("failed to save checkpoint: %v", err)
+	}
+
+	checkpoints, err := runnable.ListCheckpoints(ctx)
+	if err != nil || len(checkpoints) != 1 {
+		t.Fatalf("expected 1 checkpoint, got %d (%v)", len(checkpoints), err)
+	}
+
+	if _, err := runnable.Resume(ctx, checkpoints[0].ID); err == nil {
+		t.Error("expected error when resuming a checkpoint without pending nodes")
+	}
+}
+
+func TestCompile_UnknownInterruptNode(t *testing.T) {
+	t.Parallel()
+
+	_, err := newReviewGraph().CompileCheckpointable(graph.WithInterruptAfter("missing"))
+	if !errors.Is(err, graph.ErrNodeNotFound) {
+		t.Errorf("expected ErrNodeNotFound, got %v", err)
+	}
+}
+
+func TestInterrupt_ResumeCommand(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewCheckpointableMessageGraph()
+
+	executions := 0
+	g.AddNode("plan", func(_ context.Context, state interface{}) (interface{}, error) {
+		return fmt.Sprintf("drop table %s", state), nil
+	})
+	g.AddNode("approve", func(ctx context.Context, state interface{}) (interface{}, error) {
+		executions++
+
+		approval, err := graph.Interrupt(ctx, fmt.Sprintf("run %q?", state))
+		if err != nil {
+			return nil, err
+		}
+		reason, err := graph.Interrupt(ctx, "why?")
+		if err != nil {
+			return nil, err
+		}
+
+		return fmt.Sprintf("%s: %v (%v)", state, approval, reason), nil
+	})
+	g.AddEdge("plan", "approve")
+	g.AddEdge("approve", graph.END)
+	g.SetEntryPoint("plan")
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := runnable.Invoke(ctx, graph.Resume("too early")); err == nil {
+		t.Error("expected error when there is no interrupted run")
+	}
+
+	state, err := runnable.Invoke(ctx, "users")
+	var interrupt *graph.GraphInterrupt
+	if !errors.As(err, &interrupt) {
+		t.Fatalf("expected GraphInterrupt, got %v", err)
+	}
+	if interrupt.Node != "approve" || interrupt.Value != `run "drop table users"?` {
+		t.Errorf("unexpected interrupt: %+v", interrupt)
+	}
+	if state != "drop table users" {
+		t.Errorf("expected state before the interrupted node, got %v", state)
+	}
+
+	_, err = runnable.Invoke(ctx, graph.Resume("approved"))
+	if !errors.As(err, &interrupt) || interrupt.Value != "why?" {
+		t.Fatalf("expected second interrupt, got %v", err)
+	}
+
+	result, err := runnable.Invoke(ctx, graph.Resume("maintenance"))
+	if err != nil {
+		t.Fatalf("unexpected resume error: %v", err)
+	}
+	if result != "drop table users: approved (maintenance)" {
+		t.Errorf("unexpected result: %v", result)
+	}
+	if executions != 3 {
+		t.Errorf("expected the node to run once per resume, got %d executions", executions)
+	}
+
+	if _, err := runnable.Invoke(ctx, graph.Resume("again")); err == nil {
+		t.Error("expected error after the interrupted run was completed")
+	}
+}
+
+func TestInterrupt_ResumeSubgraph(t *testing.T) {
+	t.Parallel()
+
+	approvals := graph.NewMessageGraph()
+	approvals.AddNode("approve", func(ctx context.Context, state interface{}) (interface{}, error) {
+		answer, err := graph.Interrupt(ctx, "approve "+state.(string)+"?")
+		if err != nil {
+			return nil, err
+		}
+		return fmt.Sprintf("%s approved: %v", state, answer), nil
+	})
+	approvals.AddEdge("approve", graph.END)
+	approvals.SetEntryPoint("approve")
+
+	g := graph.NewCheckpointableMessageGraph()
+	if err := g.AddSubgraph("review", approvals); err != nil {
+		t.Fatalf("failed to add subgraph: %v", err)
+	}
+	g.AddEdge("review", graph.END)
+	g.SetEntryPoint("review")
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	_, err = runnable.InvokeWithConfig(ctx, "refund", threadConfig("subgraph"))
+
+	var interrupt *graph.GraphInterrupt
+	if !errors.As(err, &interrupt) {
+		t.Fatalf("expected GraphInterrupt, got %v", err)
+	}
+	if interrupt.Node != "review" || interrupt.Value != "approve refund?" {
+		t.Errorf("expected the subgraph to interrupt in review, got %+v", interrupt)
+	}
+
+	result, err := runnable.InvokeWithConfig(ctx, graph.Resume("yes"), threadConfig("subgraph"))
+	if err != nil {
+		t.Fatalf("unexpected resume error: %v", err)
+	}
+	if result != "refund approved: yes" {
+		t.Errorf("expected the subgraph to receive the resume value, got %v", result)
+	}
+}
+
+func TestInterrupt_ResumeSends(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewCheckpointableMessageGraph()
+	g.SetStateMerger(func(_ context.Context, current interface{}, updates []interface{}) (interface{}, error) {
+		merged := map[string]interface{}{}
+		for _, state := range append([]interface{}{current}, updates...) {
+			for key, value := range state.(map[string]interface{}) {
+				merged[key] = value
+			}
+		}
+		return merged, nil
+	})
+	g.AddNode("plan", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	g.AddNode("approve", func(ctx context.Context, state interface{}) (interface{}, error) {
+		item := state.(string)
+		answer, err := graph.Interrupt(ctx, "approve "+item+"?")
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{item: answer}, nil
+	})
+	g.AddSendEdge("plan", func(context.Context, interface{}) []graph.Send {
+		return []graph.Send{{Node: "approve", State: "refund"}, {Node: "approve", State: "upgrade"}}
+	})
+	g.AddEdge("approve", graph.END)
+	g.SetEntryPoint("plan")
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	// Each resume value goes to the send that asked for it
+	ctx := context.Background()
+	var interrupt *graph.GraphInterrupt
+	_, err = runnable.InvokeWithConfig(ctx, map[string]interface{}{}, threadConfig("sends"))
+	if !errors.As(err, &interrupt) || interrupt.Value != "approve refund?" {
+		t.Fatalf("expected the first send to interrupt, got %v", err)
+	}
+
+	_, err = runnable.InvokeWithConfig(ctx, graph.Resume("yes"), threadConfig("sends"))
+	if !errors.As(err, &interrupt) || interrupt.Value != "approve upgrade?" {
+		t.Fatalf("expected the second send to interrupt, got %v", err)
+	}
+
+	result, err := runnable.InvokeWithConfig(ctx, graph.Resume("no"), threadConfig("sends"))
+	if err != nil {
+		t.Fatalf("unexpected resume error: %v", err)
+	}
+	state := result.(map[string]interface{})
+	if state["refund"] != "yes" || state["upgrade"] != "no" {
+		t.Errorf("expected refund=yes and upgrade=no, got %v", state)
+	}
+}
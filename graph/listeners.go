@@ -133,11 +133,16 @@ func (ln *ListenableNode) NotifyListeners(ctx context.Context, event NodeEvent,
 
 // Execute runs the node function with listener notifications
 func (ln *ListenableNode) Execute(ctx context.Context, state interface{}) (interface{}, error) {
+	return ln.execute(ctx, state, ln.Function)
+}
+
+// execute runs the given function in place of the node function, with listener notifications
+func (ln *ListenableNode) execute(ctx context.Context, state interface{}, fn func(context.Context, interface{}) (interface{}, error)) (interface{}, error) {
 	// Notify start
 	ln.NotifyListeners(ctx, NodeEventStart, state, nil)
 
 	// Execute the node function
-	result, err := ln.Function(ctx, state)
+	result, err := fn(ctx, state)
 
 	// Notify completion or error
 	if err != nil {
@@ -173,17 +178,15 @@ func NewListenableMessageGraph() *ListenableMessageGraph {
 	}
 }
 
-// AddNode adds a node with listener capabilities
-func (g *ListenableMessageGraph) AddNode(name string, fn func(ctx context.Context, state interface{}) (interface{}, error)) *ListenableNode {
-	node := Node{
-		Name:     name,
-		Function: fn,
-	}
+// AddNode adds a node with listener capabilities.
+// Options such as WithMiddleware configure the node.
+func (g *ListenableMessageGraph) AddNode(name string, fn func(ctx context.Context, state interface{}) (interface{}, error), opts ...NodeOption) *ListenableNode {
+	node := newNode(name, fn, opts)
 
 	listenableNode := NewListenableNode(node)
 
 	// Add to both the base graph and our listenable nodes map
-	g.MessageGraph.AddNode(name, fn)
+	g.MessageGraph.AddNode(name, node.Function)
 	g.listenableNodes[name] = listenableNode
 
 	return listenableNode
@@ -212,57 +215,54 @@ func (g *ListenableMessageGraph) RemoveGlobalListener(listener NodeListener) {
 type ListenableRunnable struct {
 	graph           *ListenableMessageGraph
 	listenableNodes map[string]*ListenableNode
+	nodes           map[string]Node
+	tracer          *Tracer
+	options         compileOptions
 }
 
 // NewListenableRunnable creates a runnable with listener support
-func (g *ListenableMessageGraph) CompileListenable() (*ListenableRunnable, error) {
-	if g.entryPoint == "" {
-		return nil, ErrEntryPointNotSet
+func (g *ListenableMessageGraph) CompileListenable(opts ...CompileOption) (*ListenableRunnable, error) {
+	if err := g.topology().check(); err != nil {
+		return nil, err
+	}
+
+	options, err := newCompileOptions(g.nodes, opts)
+	if err != nil {
+		return nil, err
 	}
 
 	return &ListenableRunnable{
 		graph:           g,
 		listenableNodes: g.listenableNodes,
+		nodes:           withMiddleware(g.nodes, g.middleware),
+		options:         options,
 	}, nil
 }
 
 // Invoke executes the graph with listener notifications
 func (lr *ListenableRunnable) Invoke(ctx context.Context, initialState interface{}) (interface{}, error) {
-	state := initialState
-	currentNode := lr.graph.entryPoint
-
-	for {
-		if currentNode == END {
-			break
-		}
+	return lr.InvokeWithConfig(ctx, initialState, nil)
+}
 
-		listenableNode, ok := lr.listenableNodes[currentNode]
-		if !ok {
-			return nil, ErrNodeNotFound
-		}
+// InvokeWithConfig executes the graph with listener notifications and the given config.
+// When a breakpoint is hit, the state reached so far is returned with a *GraphInterrupt.
+func (lr *ListenableRunnable) InvokeWithConfig(ctx context.Context, initialState interface{}, config *Config) (interface{}, error) {
+	ctx, cancel := withConfig(ctx, config)
+	defer cancel()
 
-		var err error
-		state, err = listenableNode.Execute(ctx, state)
-		if err != nil {
-			return nil, err
-		}
-
-		// Find next node
-		foundNext := false
-		for _, edge := range lr.graph.edges {
-			if edge.From == currentNode {
-				currentNode = edge.To
-				foundNext = true
-				break
-			}
-		}
+	return lr.newEngine(config).start(ctx, initialState, config)
+}
 
-		if !foundNext {
-			return nil, ErrNoOutgoingEdge
-		}
-	}
+// SetTracer sets a tracer for observability
+func (lr *ListenableRunnable) SetTracer(tracer *Tracer) {
+	lr.tracer = tracer
+}
 
-	return state, nil
+// newEngine creates the superstep engine for one invocation, running every node
+// through its ListenableNode so that listeners are notified, then through the hooks
+// for the runnable's tracer, the graph's retry policy and the callbacks of the config
+func (lr *ListenableRunnable) newEngine(config *Config) *superstepEngine[interface{}] {
+	return newSuperstepEngine[interface{}](lr.graph.structure(), lr.nodes, lr.tracer, lr.options, config, listenerHook{nodes: lr.listenableNodes})
 }
 
 // GetGraph returns a Exporter for visualization
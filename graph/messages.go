@@ -0,0 +1,203 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// RemoveAllMessages can be used as the ID of a RemoveMessage to clear the whole history
+const RemoveAllMessages = "__remove_all__"
+
+// Message is a chat message with a stable identifier, as managed by AddMessages
+type Message struct {
+	// ID identifies the message across updates; AddMessages assigns one when empty
+	ID string `json:"id"`
+
+	// Content is the message itself
+	Content llms.MessageContent `json:"content"`
+}
+
+// RemoveMessage is an update sentinel that deletes the message with the given ID
+type RemoveMessage struct {
+	ID string `json:"id"`
+}
+
+// AddMessages is a Reducer for chat histories, similar to LangGraph's add_messages.
+// The current value is a []Message (or a []llms.MessageContent, which is given IDs).
+// The update may be a Message, an llms.MessageContent, a RemoveMessage, or a slice of any
+// of these. Maps, such as those produced by decoding a JSON checkpoint, are converted back
+// to the message they encode. Messages whose ID already exists replace the existing message
+// in place, messages without an ID are assigned a new one and appended, and RemoveMessage
+// deletes the message with its ID. The current slice is never modified.
+func AddMessages(current, update interface{}) (interface{}, error) {
+	existing, err := toMessageUpdates(current)
+	if err != nil {
+		return nil, fmt.Errorf("invalid current messages: %w", err)
+	}
+
+	updates, err := toMessageUpdates(update)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message update: %w", err)
+	}
+
+	merged := make([]Message, 0, len(existing)+len(updates))
+	index := make(map[string]int, len(existing)+len(updates))
+	removed := make(map[string]bool)
+
+	for _, item := range existing {
+		msg, ok := item.(Message)
+		if !ok {
+			return nil, fmt.Errorf("invalid current messages: unexpected %T", item)
+		}
+		index[msg.ID] = len(merged)
+		merged = append(merged, msg)
+	}
+
+	for _, item := range updates {
+		switch u := item.(type) {
+		case RemoveMessage:
+			if u.ID == RemoveAllMessages {
+				merged = merged[:0]
+				index = make(map[string]int)
+				removed = make(map[string]bool)
+				continue
+			}
+			if _, ok := index[u.ID]; !ok {
+				return nil, fmt.Errorf("cannot remove message %q: no message with that ID", u.ID)
+			}
+			removed[u.ID] = true
+		case Message:
+			if pos, ok := index[u.ID]; ok {
+				merged[pos] = u
+				delete(removed, u.ID)
+				continue
+			}
+			index[u.ID] = len(merged)
+			merged = append(merged, u)
+		}
+	}
+
+	if len(removed) == 0 {
+		return merged, nil
+	}
+
+	result := make([]Message, 0, len(merged))
+	for _, msg := range merged {
+		if !removed[msg.ID] {
+			result = append(result, msg)
+		}
+	}
+	return result, nil
+}
+
+// MessageContents returns the contents of the messages, ready to be sent to an LLM
+func MessageContents(messages []Message) []llms.MessageContent {
+	contents := make([]llms.MessageContent, len(messages))
+	for i, msg := range messages {
+		contents[i] = msg.Content
+	}
+	return contents
+}
+
+// toMessageUpdates normalizes a value into a list of Message and RemoveMessage items,
+// assigning IDs to messages that do not have one
+func toMessageUpdates(value interface{}) ([]interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case Message:
+		return []interface{}{withMessageID(v)}, nil
+	case llms.MessageContent:
+		return []interface{}{withMessageID(Message{Content: v})}, nil
+	case RemoveMessage:
+		return []interface{}{v}, nil
+	case []Message:
+		items := make([]interface{}, len(v))
+		for i, msg := range v {
+			items[i] = withMessageID(msg)
+		}
+		return items, nil
+	case []llms.MessageContent:
+		items := make([]interface{}, len(v))
+		for i, content := range v {
+			items[i] = withMessageID(Message{Content: content})
+		}
+		return items, nil
+	case []RemoveMessage:
+		items := make([]interface{}, len(v))
+		for i, remove := range v {
+			items[i] = remove
+		}
+		return items, nil
+	case map[string]interface{}:
+		item, err := messageFromMap(v)
+		if err != nil {
+			return nil, err
+		}
+		return toMessageUpdates(item)
+	case []interface{}:
+		items := make([]interface{}, 0, len(v))
+		for _, element := range v {
+			if _, nested := element.([]interface{}); nested {
+				return nil, fmt.Errorf("nested message lists are not supported")
+			}
+			elementItems, err := toMessageUpdates(element)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, elementItems...)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unsupported message type %T", value)
+	}
+}
+
+// messageFromMap converts a map decoded from JSON back into the Message, RemoveMessage or
+// llms.MessageContent it was encoded from
+func messageFromMap(value map[string]interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+
+	_, hasID := value["id"]
+	_, hasContent := value["content"]
+	_, hasRole := value["role"]
+
+	var item interface{}
+	switch {
+	case hasContent:
+		var msg Message
+		if err = json.Unmarshal(data, &msg); err == nil {
+			item = msg
+		}
+	case hasRole:
+		var content llms.MessageContent
+		if err = json.Unmarshal(data, &content); err == nil {
+			item = content
+		}
+	case hasID:
+		var remove RemoveMessage
+		if err = json.Unmarshal(data, &remove); err == nil {
+			item = remove
+		}
+	default:
+		return nil, fmt.Errorf("unsupported message map with keys %v", sortedKeys(value))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+	return item, nil
+}
+
+// withMessageID assigns a new ID to a message without one
+func withMessageID(msg Message) Message {
+	if msg.ID == "" {
+		msg.ID = uuid.New().String()
+	}
+	return msg
+}
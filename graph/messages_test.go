@@ -0,0 +1,193 @@
+package graph_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/paulnegz/langgraphgo/graph"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func messageText(msg graph.Message) string {
+	return msg.Content.Parts[0].(llms.TextContent).Text
+}
+
+func TestAddMessages_AssignsIDsAndAppends(t *testing.T) {
+	t.Parallel()
+
+	result, err := graph.AddMessages(nil, []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "hello"),
+		llms.TextParts(llms.ChatMessageTypeAI, "hi there"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	messages := result.([]graph.Message)
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+	if messages[0].ID == "" || messages[1].ID == "" || messages[0].ID == messages[1].ID {
+		t.Errorf("expected distinct IDs, got %q and %q", messages[0].ID, messages[1].ID)
+	}
+
+	result, err = graph.AddMessages(messages, llms.TextParts(llms.ChatMessageTypeHuman, "bye"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	appended := result.([]graph.Message)
+	if len(appended) != 3 || messageText(appended[2]) != "bye" {
+		t.Errorf("expected message to be appended, got %v", appended)
+	}
+	if appended[0].ID != messages[0].ID {
+		t.Error("expected existing IDs to be stable")
+	}
+	if len(messages) != 2 {
+		t.Error("current messages were modified")
+	}
+}
+
+func TestAddMessages_UpsertAndRemove(t *testing.T) {
+	t.Parallel()
+
+	current := []graph.Message{
+		{ID: "1", Content: llms.TextParts(llms.ChatMessageTypeHuman, "first")},
+		{ID: "2", Content: llms.TextParts(llms.ChatMessageTypeAI, "draft answer")},
+		{ID: "3", Content: llms.TextParts(llms.ChatMessageTypeHuman, "third")},
+	}
+
+	result, err := graph.AddMessages(current, []interface{}{
+		graph.Message{ID: "2", Content: llms.TextParts(llms.ChatMessageTypeAI, "edited answer")},
+		graph.RemoveMessage{ID: "1"},
+		graph.Message{ID: "4", Content: llms.TextParts(llms.ChatMessageTypeAI, "fourth")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	messages := result.([]graph.Message)
+	expected := []string{"edited answer", "third", "fourth"}
+	if len(messages) != len(expected) {
+		t.Fatalf("expected %d messages, got %d", len(expected), len(messages))
+	}
+	for i, text := range expected {
+		if messageText(messages[i]) != text {
+			t.Errorf("message %d: expected %q, got %q", i, text, messageText(messages[i]))
+		}
+	}
+
+	if messageText(current[1]) != "draft answer" {
+		t.Error("current messages were modified")
+	}
+}
+
+func TestAddMessages_RemoveErrors(t *testing.T) {
+	t.Parallel()
+
+	current := []graph.Message{{ID: "1", Content: llms.TextParts(llms.ChatMessageTypeHuman, "only")}}
+
+	if _, err := graph.AddMessages(current, graph.RemoveMessage{ID: "missing"}); err == nil {
+		t.Error("expected error when removing unknown message")
+	}
+
+	if _, err := graph.AddMessages(current, 42); err == nil {
+		t.Error("expected error for unsupported update type")
+	}
+
+	result, err := graph.AddMessages(current, graph.RemoveMessage{ID: graph.RemoveAllMessages})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.([]graph.Message)) != 0 {
+		t.Errorf("expected all messages to be removed, got %v", result)
+	}
+}
+
+func TestAddMessages_DecodedJSON(t *testing.T) {
+	t.Parallel()
+
+	// A history restored from a JSON checkpoint holds maps instead of messages
+	data, err := json.Marshal([]graph.Message{
+		{ID: "1", Content: llms.TextParts(llms.ChatMessageTypeHuman, "hello")},
+		{ID: "2", Content: llms.TextParts(llms.ChatMessageTypeAI, "hi there")},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal messages: %v", err)
+	}
+	var current interface{}
+	if err := json.Unmarshal(data, &current); err != nil {
+		t.Fatalf("failed to unmarshal messages: %v", err)
+	}
+
+	result, err := graph.AddMessages(current, []interface{}{
+		map[string]interface{}{"id": "1"},
+		map[string]interface{}{"role": "human", "text": "bye"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	messages := result.([]graph.Message)
+	if len(messages) != 2 || messages[0].ID != "2" || messageText(messages[0]) != "hi there" || messageText(messages[1]) != "bye" {
+		t.Errorf("expected the decoded history to be updated, got %v", messages)
+	}
+
+	if _, err := graph.AddMessages([]interface{}{graph.RemoveMessage{ID: "1"}}, nil); err == nil {
+		t.Error("expected error for a removal in the current messages")
+	}
+	if _, err := graph.AddMessages(map[string]interface{}{"text": "hello"}, nil); err == nil {
+		t.Error("expected error for a map that is not a message")
+	}
+}
+
+func TestAddMessages_AsStateGraphChannel(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewStateGraph()
+	g.SetSchema(graph.NewMapSchema().AddChannel("messages", graph.AddMessages))
+
+	g.AddNode("respond", func(_ context.Context, _ interface{}) (interface{}, error) {
+		return map[string]interface{}{
+			"messages": graph.Message{
+				ID:      "answer",
+				Content: llms.TextParts(llms.ChatMessageTypeAI, "draft"),
+			},
+		}, nil
+	})
+	g.AddNode("revise", func(_ context.Context, state interface{}) (interface{}, error) {
+		messages := state.(map[string]interface{})["messages"].([]graph.Message)
+		return map[string]interface{}{
+			"messages": []interface{}{
+				graph.Message{ID: "answer", Content: llms.TextParts(llms.ChatMessageTypeAI, "final")},
+				graph.RemoveMessage{ID: messages[0].ID},
+			},
+		}, nil
+	})
+	g.AddEdge("respond", "revise")
+	g.AddEdge("revise", graph.END)
+	g.SetEntryPoint("respond")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), map[string]interface{}{
+		"messages": []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "question")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected invoke error: %v", err)
+	}
+
+	messages := result.(map[string]interface{})["messages"].([]graph.Message)
+	if len(messages) != 1 || messages[0].ID != "answer" || messageText(messages[0]) != "final" {
+		t.Errorf("unexpected messages: %v", messages)
+	}
+
+	contents := graph.MessageContents(messages)
+	if len(contents) != 1 || contents[0].Role != llms.ChatMessageTypeAI {
+		t.Errorf("unexpected contents: %v", contents)
+	}
+}
@@ -0,0 +1,96 @@
+package graph
+
+import "context"
+
+// TypedNodeFunc is the function of a node of a graph whose state has the static type S
+type TypedNodeFunc[S any] func(ctx context.Context, state S) (S, error)
+
+// NodeFunc is the function of a node of a graph with untyped state
+type NodeFunc = TypedNodeFunc[interface{}]
+
+// TypedMiddleware wraps the function of a node, e.g. to retry it, bound its duration or
+// limit how often it runs. It receives the next function of the chain, which it may call
+// any number of times. A middleware is applied once per node, so state it creates when
+// wrapping, such as the state of a circuit breaker, belongs to that node.
+type TypedMiddleware[S any] func(next TypedNodeFunc[S]) TypedNodeFunc[S]
+
+// Middleware wraps the function of a node of a graph with untyped state
+type Middleware = TypedMiddleware[interface{}]
+
+// TypedNodeOption configures a node when it is added to a graph
+type TypedNodeOption[S any] func(*nodeOptions[S])
+
+// NodeOption configures a node when it is added to a graph with untyped state
+type NodeOption = TypedNodeOption[interface{}]
+
+// nodeOptions holds the settings collected from the options passed to AddNode
+type nodeOptions[S any] struct {
+	middleware []TypedMiddleware[S]
+}
+
+// WithMiddleware wraps the function of the node with the given middlewares. The first
+// middleware is the outermost: with WithMiddleware(RetryMiddleware(config),
+// TimeoutMiddleware(d)), every attempt of the node gets its own timeout.
+func WithMiddleware[S any](middleware ...TypedMiddleware[S]) TypedNodeOption[S] {
+	return func(o *nodeOptions[S]) {
+		o.middleware = append(o.middleware, middleware...)
+	}
+}
+
+// newNode creates a node whose function is wrapped by the middlewares of the given options
+func newNode[S any](name string, fn TypedNodeFunc[S], opts []TypedNodeOption[S]) TypedNode[S] {
+	var options nodeOptions[S]
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return TypedNode[S]{
+		Name:     name,
+		Function: chain(name, fn, options.middleware),
+	}
+}
+
+// chain wraps the function of a node with middlewares, the first being the outermost.
+// The middlewares run with the name of the node in their context.
+func chain[S any](name string, fn TypedNodeFunc[S], middleware []TypedMiddleware[S]) TypedNodeFunc[S] {
+	if len(middleware) == 0 {
+		return fn
+	}
+
+	for i := len(middleware) - 1; i >= 0; i-- {
+		fn = middleware[i](fn)
+	}
+	return func(ctx context.Context, state S) (S, error) {
+		return fn(withNodeName(ctx, name), state)
+	}
+}
+
+// nodeNameKey is the context key for the name of the node whose middlewares are running
+type nodeNameKey struct{}
+
+// withNodeName returns a context naming the node whose middlewares run with it
+func withNodeName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, nodeNameKey{}, name)
+}
+
+// nodeName returns the name of the node whose middlewares run with ctx, for their errors
+func nodeName(ctx context.Context) string {
+	name, _ := ctx.Value(nodeNameKey{}).(string)
+	return name
+}
+
+// withMiddleware returns the nodes of a graph wrapped by the graph-wide middlewares, which
+// wrap the middlewares of each node. Without graph-wide middlewares, the nodes are returned
+// as they are.
+func withMiddleware[S any](nodes map[string]TypedNode[S], middleware []TypedMiddleware[S]) map[string]TypedNode[S] {
+	if len(middleware) == 0 {
+		return nodes
+	}
+
+	wrapped := make(map[string]TypedNode[S], len(nodes))
+	for name, node := range nodes {
+		node.Function = chain(name, node.Function, middleware)
+		wrapped[name] = node
+	}
+	return wrapped
+}
@@ -0,0 +1,303 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+// recordingMiddleware appends enter and exit markers around the calls of a node
+func recordingMiddleware(name string, calls *[]string) graph.Middleware {
+	return func(next graph.NodeFunc) graph.NodeFunc {
+		return func(ctx context.Context, state interface{}) (interface{}, error) {
+			*calls = append(*calls, name+">")
+			result, err := next(ctx, state)
+			*calls = append(*calls, "<"+name)
+			return result, err
+		}
+	}
+}
+
+func TestMiddleware_Order(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	g := graph.NewMessageGraph()
+	g.Use(recordingMiddleware("graph", &calls))
+	g.AddNode("node", func(_ context.Context, state interface{}) (interface{}, error) {
+		calls = append(calls, "node")
+		return state, nil
+	}, graph.WithMiddleware(recordingMiddleware("outer", &calls), recordingMiddleware("inner", &calls)))
+	g.AddEdge("node", graph.END)
+	g.SetEntryPoint("node")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+	if _, err := runnable.Invoke(context.Background(), "input"); err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+
+	want := "graph> outer> inner> node <inner <outer <graph"
+	if got := strings.Join(calls, " "); got != want {
+		t.Errorf("Expected calls %q, got %q", want, got)
+	}
+}
+
+func TestMiddleware_RetryWithTimeoutPerAttempt(t *testing.T) {
+	t.Parallel()
+
+	attempts := int32(0)
+	g := graph.NewMessageGraph()
+	g.AddNode("slow_once", func(ctx context.Context, _ interface{}) (interface{}, error) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return successResult, nil
+	}, graph.WithMiddleware(
+		graph.RetryMiddleware(&graph.RetryConfig{
+			MaxAttempts:   3,
+			InitialDelay:  time.Millisecond,
+			MaxDelay:      time.Millisecond,
+			BackoffFactor: 1,
+		}),
+		graph.TimeoutMiddleware(20*time.Millisecond),
+	))
+	g.AddEdge("slow_once", graph.END)
+	g.SetEntryPoint("slow_once")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), "input")
+	if err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+	if result != successResult {
+		t.Errorf("Expected %q, got %v", successResult, result)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("Expected 2 attempts, got %d", got)
+	}
+}
+
+func TestMiddleware_CircuitBreakerPerNode(t *testing.T) {
+	t.Parallel()
+
+	errBoom := errors.New("boom")
+	g := graph.NewMessageGraph()
+	g.Use(graph.CircuitBreakerMiddleware(graph.CircuitBreakerConfig{
+		FailureThreshold: 1,
+		SuccessThreshold: 1,
+		Timeout:          time.Hour,
+		HalfOpenMaxCalls: 1,
+	}))
+	g.AddNode("failing", func(context.Context, interface{}) (interface{}, error) {
+		return nil, errBoom
+	})
+	g.AddNode("healthy", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	g.AddNode("route", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	g.AddConditionalEdgeWithTargets("route", func(_ context.Context, state interface{}) string {
+		return state.(string)
+	}, "failing", "healthy")
+	g.AddEdge("failing", graph.END)
+	g.AddEdge("healthy", graph.END)
+	g.SetEntryPoint("route")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+
+	if _, err := runnable.Invoke(context.Background(), "failing"); !errors.Is(err, errBoom) {
+		t.Fatalf("Expected the node error, got %v", err)
+	}
+	_, err = runnable.Invoke(context.Background(), "failing")
+	if err == nil || !strings.Contains(err.Error(), "circuit breaker open for failing") {
+		t.Fatalf("Expected the circuit to be open, got %v", err)
+	}
+
+	// The circuit of the failing node does not affect the other nodes
+	if _, err := runnable.Invoke(context.Background(), "healthy"); err != nil {
+		t.Errorf("Expected the healthy node to run, got %v", err)
+	}
+}
+
+func TestMiddleware_TypedStateGraph(t *testing.T) {
+	t.Parallel()
+
+	type counter struct{ Count int }
+
+	double := func(next graph.TypedNodeFunc[counter]) graph.TypedNodeFunc[counter] {
+		return func(ctx context.Context, state counter) (counter, error) {
+			state, err := next(ctx, state)
+			state.Count *= 2
+			return state, err
+		}
+	}
+
+	g := graph.NewTypedStateGraph[counter]()
+	g.AddNode("increment", func(_ context.Context, state counter) (counter, error) {
+		state.Count++
+		return state, nil
+	}, graph.WithMiddleware(double))
+	g.AddEdge("increment", graph.END)
+	g.SetEntryPoint("increment")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), counter{Count: 1})
+	if err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+	if result.Count != 4 {
+		t.Errorf("Expected count 4, got %d", result.Count)
+	}
+}
+
+func TestMiddleware_Interrupts(t *testing.T) {
+	t.Parallel()
+
+	calls := int32(0)
+	g := graph.NewCheckpointableMessageGraph()
+	g.AddNode("approve", func(ctx context.Context, state interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		answer, err := graph.Interrupt(ctx, "approve?")
+		if err != nil {
+			return nil, err
+		}
+		return answer, nil
+	}, graph.WithMiddleware(
+		graph.CircuitBreakerMiddleware(graph.CircuitBreakerConfig{
+			FailureThreshold: 1,
+			SuccessThreshold: 1,
+			Timeout:          time.Hour,
+			HalfOpenMaxCalls: 1,
+		}),
+		graph.RetryMiddleware(&graph.RetryConfig{
+			MaxAttempts:   3,
+			InitialDelay:  time.Second,
+			BackoffFactor: 1,
+			RetryableErrors: func(error) bool {
+				t.Error("expected interrupts not to be checked for retries")
+				return true
+			},
+		}),
+	))
+	g.AddEdge("approve", graph.END)
+	g.SetEntryPoint("approve")
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+
+	// Every pause is reported at once, and keeps the circuit closed
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		_, err := runnable.InvokeWithConfig(ctx, "input", threadConfig("approvals"))
+		if !errors.Is(err, graph.ErrGraphInterrupted) {
+			t.Fatalf("Expected an interrupt, got %v", err)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("Expected one call per run, got %d", got)
+	}
+
+	result, err := runnable.InvokeWithConfig(ctx, graph.Resume("yes"), threadConfig("approvals"))
+	if err != nil {
+		t.Fatalf("Expected the resumed run to pass the circuit breaker, got %v", err)
+	}
+	if result != "yes" {
+		t.Errorf("Expected yes, got %v", result)
+	}
+}
+
+func TestMiddleware_ErrorsNameTheNode(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewMessageGraph()
+	g.AddNode("flaky", func(context.Context, interface{}) (interface{}, error) {
+		return nil, errors.New("boom")
+	}, graph.WithMiddleware(graph.RetryMiddleware(&graph.RetryConfig{
+		MaxAttempts:   2,
+		InitialDelay:  time.Millisecond,
+		BackoffFactor: 1,
+	})))
+	g.AddEdge("flaky", graph.END)
+	g.SetEntryPoint("flaky")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+
+	_, err = runnable.Invoke(context.Background(), "input")
+	if err == nil || !strings.Contains(err.Error(), "max retries (2) exceeded for flaky: boom") {
+		t.Errorf("Expected the retry error to name the node, got %v", err)
+	}
+}
+
+func TestMiddleware_TypedBuiltins(t *testing.T) {
+	t.Parallel()
+
+	type counter struct{ Count int }
+
+	attempts := int32(0)
+	g := graph.NewTypedStateGraph[counter]()
+	g.Use(graph.TypedCircuitBreakerMiddleware[counter](graph.CircuitBreakerConfig{
+		FailureThreshold: 2,
+		SuccessThreshold: 1,
+		Timeout:          time.Hour,
+		HalfOpenMaxCalls: 1,
+	}))
+	g.AddNode("increment", func(_ context.Context, state counter) (counter, error) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			return counter{}, errors.New("flaky")
+		}
+		state.Count++
+		return state, nil
+	}, graph.WithMiddleware(
+		graph.TypedRateLimitMiddleware[counter](10, time.Minute),
+		graph.TypedRetryMiddleware[counter](&graph.RetryConfig{
+			MaxAttempts:   2,
+			InitialDelay:  time.Millisecond,
+			BackoffFactor: 1,
+		}),
+		graph.TypedTimeoutMiddleware[counter](time.Second),
+	))
+	g.AddEdge("increment", graph.END)
+	g.SetEntryPoint("increment")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), counter{Count: 1})
+	if err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+	if result.Count != 2 {
+		t.Errorf("Expected count 2, got %d", result.Count)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("Expected 2 attempts, got %d", got)
+	}
+}
@@ -0,0 +1,68 @@
+package graph
+
+import "fmt"
+
+// CompileOption configures a graph when it is compiled into a runnable
+type CompileOption func(*compileOptions)
+
+// compileOptions holds the settings collected from the CompileOptions passed to Compile
+type compileOptions struct {
+	interruptBefore []string
+	interruptAfter  []string
+}
+
+// WithInterruptBefore pauses every run before the given nodes execute
+func WithInterruptBefore(nodes ...string) CompileOption {
+	return func(o *compileOptions) {
+		o.interruptBefore = append(o.interruptBefore, nodes...)
+	}
+}
+
+// WithInterruptAfter pauses every run after the given nodes execute
+func WithInterruptAfter(nodes ...string) CompileOption {
+	return func(o *compileOptions) {
+		o.interruptAfter = append(o.interruptAfter, nodes...)
+	}
+}
+
+// newCompileOptions applies the given options and checks that every node they refer to exists
+func newCompileOptions[S any](nodes map[string]TypedNode[S], opts []CompileOption) (compileOptions, error) {
+	var options compileOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	for _, names := range [][]string{options.interruptBefore, options.interruptAfter} {
+		for _, name := range names {
+			if _, ok := nodes[name]; !ok {
+				return compileOptions{}, fmt.Errorf("invalid interrupt: %w: %s", ErrNodeNotFound, name)
+			}
+		}
+	}
+
+	return options, nil
+}
+
+// interrupts combines the breakpoints set at compile time with those of the given config
+func (o compileOptions) interrupts(config *Config) (before, after map[string]bool) {
+	before = make(map[string]bool)
+	after = make(map[string]bool)
+
+	for _, name := range o.interruptBefore {
+		before[name] = true
+	}
+	for _, name := range o.interruptAfter {
+		after[name] = true
+	}
+
+	if config != nil {
+		for _, name := range config.InterruptBefore {
+			before[name] = true
+		}
+		for _, name := range config.InterruptAfter {
+			after[name] = true
+		}
+	}
+
+	return before, after
+}
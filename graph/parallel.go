@@ -8,8 +8,9 @@ import (
 
 // ParallelNode represents a set of nodes that can execute in parallel
 type ParallelNode struct {
-	nodes []Node
-	name  string
+	nodes  []Node
+	name   string
+	schema StateSchema[interface{}]
 }
 
 // NewParallelNode creates a new parallel node
@@ -20,7 +21,17 @@ func NewParallelNode(name string, nodes ...Node) *ParallelNode {
 	}
 }
 
-// Execute runs all nodes in parallel and collects results
+// WithSchema makes the parallel node combine the partial updates of its nodes through
+// the schema's reducers, returning a single update instead of a slice of results
+func (pn *ParallelNode) WithSchema(schema StateSchema[interface{}]) *ParallelNode {
+	pn.schema = schema
+	return pn
+}
+
+// Execute runs all nodes in parallel and collects results.
+// When the graph is checkpointed, the result of every node that completes is kept as a
+// pending write, so that if another node fails, resuming the step only runs the nodes that
+// did not complete.
 func (pn *ParallelNode) Execute(ctx context.Context, state interface{}) (interface{}, error) {
 	// Create channels for results and errors
 	type result struct {
@@ -32,8 +43,23 @@ func (pn *ParallelNode) Execute(ctx context.Context, state interface{}) (interfa
 	results := make(chan result, len(pn.nodes))
 	var wg sync.WaitGroup
 
+	pendingWrites := pendingWritesFromContext(ctx)
+
 	// Execute all nodes in parallel
 	for i, node := range pn.nodes {
+		branch := node.Name
+		if branch == "" {
+			branch = fmt.Sprint(i)
+		}
+
+		if pendingWrites != nil {
+			if value, ok := pendingWrites.lookup(branch); ok {
+				pendingWrites.record(branch, value)
+				results <- result{index: i, value: value}
+				continue
+			}
+		}
+
 		wg.Add(1)
 		go func(idx int, n Node) {
 			defer wg.Done()
@@ -49,6 +75,9 @@ func (pn *ParallelNode) Execute(ctx context.Context, state interface{}) (interfa
 			}()
 
 			value, err := n.Function(ctx, state)
+			if err == nil && pendingWrites != nil {
+				pendingWrites.record(branch, value)
+			}
 			results <- result{
 				index: idx,
 				value: value,
@@ -78,6 +107,19 @@ func (pn *ParallelNode) Execute(ctx context.Context, state interface{}) (interfa
 		return nil, fmt.Errorf("parallel execution failed: %w", firstError)
 	}
 
+	if pn.schema != nil {
+		// Combine the updates in node order, starting from an empty update
+		var combined interface{}
+		for _, output := range outputs {
+			var err error
+			combined, err = pn.schema.Update(combined, output)
+			if err != nil {
+				return nil, fmt.Errorf("failed to combine results of parallel node %s: %w", pn.name, err)
+			}
+		}
+		return combined, nil
+	}
+
 	// Return collected results
 	return outputs, nil
 }
@@ -103,6 +145,7 @@ type MapReduceNode struct {
 	name     string
 	mapNodes []Node
 	reducer  func([]interface{}) (interface{}, error)
+	schema   StateSchema[interface{}]
 }
 
 // NewMapReduceNode creates a new map-reduce node
@@ -114,10 +157,21 @@ func NewMapReduceNode(name string, reducer func([]interface{}) (interface{}, err
 	}
 }
 
+// WithSchema makes the map-reduce node combine the partial updates of its map nodes through
+// the schema's reducers when it has no reducer of its own, returning a single update instead
+// of a slice of results. A reducer, if set, still receives the results of the map nodes.
+func (mr *MapReduceNode) WithSchema(schema StateSchema[interface{}]) *MapReduceNode {
+	mr.schema = schema
+	return mr
+}
+
 // Execute runs map nodes in parallel and reduces results
 func (mr *MapReduceNode) Execute(ctx context.Context, state interface{}) (interface{}, error) {
 	// Execute map phase in parallel
 	pn := NewParallelNode(mr.name+"_map", mr.mapNodes...)
+	if mr.reducer == nil {
+		pn.WithSchema(mr.schema)
+	}
 	results, err := pn.Execute(ctx, state)
 	if err != nil {
 		return nil, fmt.Errorf("map phase failed: %w", err)
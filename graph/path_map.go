@@ -0,0 +1,50 @@
+package graph
+
+import "sort"
+
+// copyPathMap copies a path map so that later changes by the caller don't affect the graph
+func copyPathMap(pathMap map[string]string) map[string]string {
+	paths := make(map[string]string, len(pathMap))
+	for key, target := range pathMap {
+		paths[key] = target
+	}
+	return paths
+}
+
+// targetPathMap builds the path map of a condition that returns the names of its targets
+func targetPathMap(targets []string) map[string]string {
+	paths := make(map[string]string, len(targets))
+	for _, target := range targets {
+		paths[target] = target
+	}
+	return paths
+}
+
+// branches returns the labeled edges of the path map of a node, sorted by label. Targets
+// given as a list have no label.
+func branches(pathMap map[string]string) []branch {
+	result := make([]branch, 0, len(pathMap))
+	for _, key := range sortedKeys(pathMap) {
+		label := key
+		if key == pathMap[key] {
+			label = ""
+		}
+		result = append(result, branch{label: label, to: pathMap[key]})
+	}
+	return result
+}
+
+// branch is an edge a conditional edge can take
+type branch struct {
+	label string
+	to    string
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,205 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// PendingWrite is the output of a task that completed in a step that did not finish, saved
+// so that resuming the step does not run the task again. A task is a node, or a branch of a
+// ParallelNode identified as "<node>/<branch>".
+type PendingWrite struct {
+	// TaskID identifies the node or branch that produced the value
+	TaskID string `json:"task_id"`
+
+	// Value is the output of the task. For a task that returned a Command, it is the
+	// update of the command.
+	Value interface{} `json:"value"`
+
+	// Command reports that the task returned a Command, which routed to Goto and Sends.
+	// The command is rebuilt from the write when the step is resumed, so that it survives
+	// stores that persist writes as JSON.
+	Command bool `json:"command,omitempty"`
+
+	// Goto lists the nodes the command routed to. Without Goto and Sends, the command
+	// followed the edges of its node.
+	Goto []string `json:"goto,omitempty"`
+
+	// Sends lists the sends the command routed to
+	Sends []Send `json:"sends,omitempty"`
+}
+
+// newPendingWrite creates the write of a task's output. A command is saved as its update
+// and the nodes it routes to. It reports false for commands that cannot be saved: those for
+// the parent graph and those with an invalid Goto, whose tasks run again on resume.
+func newPendingWrite(taskID string, value interface{}) (PendingWrite, bool) {
+	command, ok := commandOf(value)
+	if !ok {
+		return PendingWrite{TaskID: taskID, Value: value}, true
+	}
+	if command.Graph == CommandParent {
+		return PendingWrite{}, false
+	}
+
+	nodes, sends, err := command.targets()
+	if err != nil {
+		return PendingWrite{}, false
+	}
+
+	// A command routing nowhere ends its branch, like one routing to END
+	if command.Goto != nil && len(nodes) == 0 && len(sends) == 0 {
+		nodes = []string{END}
+	}
+
+	return PendingWrite{TaskID: taskID, Value: command.Update, Command: true, Goto: nodes, Sends: sends}, true
+}
+
+// output returns the output of the task that produced the write, rebuilding its command
+func (w PendingWrite) output() interface{} {
+	if !w.Command {
+		return w.Value
+	}
+
+	command := &Command{Update: w.Value}
+	switch {
+	case len(w.Sends) > 0:
+		command.Goto = w.Sends
+	case len(w.Goto) > 0:
+		command.Goto = w.Goto
+	}
+	return command
+}
+
+// pendingWriteRecord is the form in which checkpoint stores persist a PendingWrite, with
+// its value and the states of its sends encoded by the store's serializer like a
+// checkpoint state
+type pendingWriteRecord struct {
+	TaskID       string          `json:"task_id"`
+	Value        json.RawMessage `json:"value,omitempty"`
+	EncodedValue []byte          `json:"encoded_value,omitempty"`
+	Command      bool            `json:"command,omitempty"`
+	Goto         []string        `json:"goto,omitempty"`
+	Sends        []sendRecord    `json:"sends,omitempty"`
+}
+
+// encodePendingWrites encodes pending writes as records with the given serializer
+func encodePendingWrites(serializer Serializer, writes []PendingWrite) ([]pendingWriteRecord, error) {
+	if len(writes) == 0 {
+		return nil, nil
+	}
+
+	records := make([]pendingWriteRecord, len(writes))
+	for i, write := range writes {
+		value, encodedValue, err := encodeState(serializer, write.Value)
+		if err != nil {
+			return nil, err
+		}
+		sends, err := encodeSends(serializer, write.Sends)
+		if err != nil {
+			return nil, err
+		}
+		records[i] = pendingWriteRecord{
+			TaskID:       write.TaskID,
+			Value:        value,
+			EncodedValue: encodedValue,
+			Command:      write.Command,
+			Goto:         write.Goto,
+			Sends:        sends,
+		}
+	}
+	return records, nil
+}
+
+// decodePendingWrites decodes records encoded by encodePendingWrites
+func decodePendingWrites(serializer Serializer, records []pendingWriteRecord) ([]PendingWrite, error) {
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	writes := make([]PendingWrite, len(records))
+	for i, record := range records {
+		value, err := decodeState(serializer, record.Value, record.EncodedValue)
+		if err != nil {
+			return nil, err
+		}
+		sends, err := decodeSends(serializer, record.Sends)
+		if err != nil {
+			return nil, err
+		}
+		writes[i] = PendingWrite{TaskID: record.TaskID, Value: value, Command: record.Command, Goto: record.Goto, Sends: sends}
+	}
+	return writes, nil
+}
+
+// pendingWritesKey is the context key for the pending writes of a node execution
+type pendingWritesKey struct{}
+
+// pendingWriteScratch holds the pending writes of one node execution: those saved by an
+// earlier attempt of the step, and those recorded by branches completing in this one
+type pendingWriteScratch struct {
+	node     string
+	previous map[string]interface{}
+
+	mutex    sync.Mutex
+	recorded []PendingWrite
+}
+
+// newPendingWriteScratch creates the scratch of a node from the pending writes of its step
+func newPendingWriteScratch(node string, writes []PendingWrite) *pendingWriteScratch {
+	scratch := &pendingWriteScratch{node: node}
+	for _, write := range writes {
+		if write.TaskID == node || strings.HasPrefix(write.TaskID, node+"/") {
+			if scratch.previous == nil {
+				scratch.previous = make(map[string]interface{})
+			}
+			scratch.previous[write.TaskID] = write.output()
+		}
+	}
+	return scratch
+}
+
+// taskID returns the ID of a branch of the node, or of the node itself for an empty branch
+func (s *pendingWriteScratch) taskID(branch string) string {
+	if branch == "" {
+		return s.node
+	}
+	return s.node + "/" + branch
+}
+
+// lookup returns the value a branch of the node, or the node itself for an empty branch,
+// produced in an earlier attempt of the step
+func (s *pendingWriteScratch) lookup(branch string) (interface{}, bool) {
+	value, ok := s.previous[s.taskID(branch)]
+	return value, ok
+}
+
+// record saves the value produced by a branch of the node
+func (s *pendingWriteScratch) record(branch string, value interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if write, ok := newPendingWrite(s.taskID(branch), value); ok {
+		s.recorded = append(s.recorded, write)
+	}
+}
+
+// writes returns the writes recorded for completed branches, including the reused ones
+func (s *pendingWriteScratch) writes() []PendingWrite {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]PendingWrite(nil), s.recorded...)
+}
+
+// withPendingWrites returns a context carrying the pending writes of a node execution
+func withPendingWrites(ctx context.Context, scratch *pendingWriteScratch) context.Context {
+	return context.WithValue(ctx, pendingWritesKey{}, scratch)
+}
+
+// pendingWritesFromContext returns the pending writes of the node executing with ctx, if any
+func pendingWritesFromContext(ctx context.Context) *pendingWriteScratch {
+	scratch, _ := ctx.Value(pendingWritesKey{}).(*pendingWriteScratch)
+	return scratch
+}
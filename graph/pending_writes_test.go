@@ -0,0 +1,301 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+// taskCounter counts the runs of each task and fails the first run of one of them
+type taskCounter struct {
+	mutex  sync.Mutex
+	runs   map[string]int
+	flaky  string
+	failed bool
+}
+
+func (c *taskCounter) task(name string) func(context.Context, interface{}) (interface{}, error) {
+	return func(_ context.Context, _ interface{}) (interface{}, error) {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+
+		c.runs[name]++
+		if name == c.flaky && !c.failed {
+			c.failed = true
+			return nil, errors.New("temporary outage")
+		}
+		return map[string]interface{}{name: "done"}, nil
+	}
+}
+
+func TestCheckpointableRunnable_PendingWrites(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	counter := &taskCounter{runs: map[string]int{}, flaky: "b"}
+
+	g := graph.NewCheckpointableMessageGraphWithConfig(graph.CheckpointConfig{Store: graph.NewMemoryCheckpointStore()})
+	g.SetStateMerger(func(_ context.Context, current interface{}, updates []interface{}) (interface{}, error) {
+		merged := map[string]interface{}{}
+		for _, state := range append([]interface{}{current}, updates...) {
+			for key, value := range state.(map[string]interface{}) {
+				merged[key] = value
+			}
+		}
+		return merged, nil
+	})
+	g.AddNode("start", counter.task("start"))
+	for _, name := range []string{"a", "b", "c"} {
+		g.AddNode(name, counter.task(name))
+		g.AddEdge("start", name)
+		g.AddEdge(name, graph.END)
+	}
+	g.SetEntryPoint("start")
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	if _, err := runnable.InvokeWithConfig(ctx, map[string]interface{}{}, threadConfig("fan-out")); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+
+	history, err := runnable.GetStateHistory(ctx, "fan-out")
+	if err != nil || len(history) != 1 {
+		t.Fatalf("expected a checkpoint for the failed step, got %d (%v)", len(history), err)
+	}
+	checkpoint, err := runnable.LoadCheckpoint(ctx, history[0].CheckpointID)
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+	if checkpoint.NodeName != "b" || checkpoint.Metadata["event"] != "error" || len(checkpoint.Next) != 3 {
+		t.Errorf("unexpected checkpoint for the failed step: %+v", checkpoint)
+	}
+	if len(checkpoint.PendingWrites) != 2 || checkpoint.PendingWrites[0].TaskID != "a" || checkpoint.PendingWrites[1].TaskID != "c" {
+		t.Errorf("expected pending writes for a and c, got %+v", checkpoint.PendingWrites)
+	}
+
+	result, err := runnable.InvokeWithConfig(ctx, nil, threadConfig("fan-out"))
+	if err != nil {
+		t.Fatalf("unexpected error on resume: %v", err)
+	}
+	if state, _ := result.(map[string]interface{}); len(state) != 4 {
+		t.Errorf("expected the writes of every task, got %v", result)
+	}
+
+	expected := map[string]int{"start": 1, "a": 1, "b": 2, "c": 1}
+	for name, runs := range expected {
+		if counter.runs[name] != runs {
+			t.Errorf("expected %s to run %d times, got %d", name, runs, counter.runs[name])
+		}
+	}
+}
+
+func TestCheckpointableRunnable_InterruptPendingWrites(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	counter := &taskCounter{runs: map[string]int{}}
+
+	g := graph.NewCheckpointableMessageGraphWithConfig(graph.CheckpointConfig{Store: graph.NewMemoryCheckpointStore()})
+	g.SetStateMerger(func(_ context.Context, current interface{}, updates []interface{}) (interface{}, error) {
+		merged := map[string]interface{}{}
+		for _, state := range append([]interface{}{current}, updates...) {
+			for key, value := range state.(map[string]interface{}) {
+				merged[key] = value
+			}
+		}
+		return merged, nil
+	})
+	g.AddNode("start", counter.task("start"))
+	g.AddNode("approve", func(ctx context.Context, _ interface{}) (interface{}, error) {
+		counter.task("approve")(ctx, nil)
+		answer, err := graph.Interrupt(ctx, "approve?")
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"approve": answer}, nil
+	})
+	g.AddEdge("start", "approve")
+	g.AddEdge("approve", graph.END)
+	for _, name := range []string{"a", "c"} {
+		g.AddNode(name, counter.task(name))
+		g.AddEdge("start", name)
+		g.AddEdge(name, graph.END)
+	}
+	g.SetEntryPoint("start")
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	if _, err := runnable.InvokeWithConfig(ctx, map[string]interface{}{}, threadConfig("approval")); !errors.Is(err, graph.ErrGraphInterrupted) {
+		t.Fatalf("expected an interrupt, got %v", err)
+	}
+
+	state, err := runnable.GetState(ctx, "approval")
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+	checkpoint, err := runnable.LoadCheckpoint(ctx, state.CheckpointID)
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+	if len(checkpoint.PendingWrites) != 2 || checkpoint.PendingWrites[0].TaskID != "a" || checkpoint.PendingWrites[1].TaskID != "c" {
+		t.Errorf("expected pending writes for a and c, got %+v", checkpoint.PendingWrites)
+	}
+
+	result, err := runnable.InvokeWithConfig(ctx, graph.Resume("yes"), threadConfig("approval"))
+	if err != nil {
+		t.Fatalf("unexpected error on resume: %v", err)
+	}
+	if merged, _ := result.(map[string]interface{}); len(merged) != 4 || merged["approve"] != "yes" {
+		t.Errorf("expected the writes of every task, got %v", result)
+	}
+
+	// Only the interrupted task runs again
+	for name, runs := range map[string]int{"start": 1, "a": 1, "approve": 2, "c": 1} {
+		if counter.runs[name] != runs {
+			t.Errorf("expected %s to run %d times, got %d", name, runs, counter.runs[name])
+		}
+	}
+}
+
+func TestParallelNode_PendingWrites(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := graph.NewFileCheckpointStoreWithDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	counter := &taskCounter{runs: map[string]int{}, flaky: "y"}
+
+	g := graph.NewCheckpointableMessageGraphWithConfig(graph.CheckpointConfig{Store: store})
+	g.AddParallelNodes("fanout", map[string]func(context.Context, interface{}) (interface{}, error){
+		"x": counter.task("x"),
+		"y": counter.task("y"),
+		"z": counter.task("z"),
+	})
+	g.AddEdge("fanout", graph.END)
+	g.SetEntryPoint("fanout")
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	if _, err := runnable.InvokeWithConfig(ctx, "input", threadConfig("branches")); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+
+	state, err := runnable.GetState(ctx, "branches")
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+
+	result, err := runnable.Resume(ctx, state.CheckpointID)
+	if err != nil {
+		t.Fatalf("unexpected error on resume: %v", err)
+	}
+	if outputs, _ := result.([]interface{}); len(outputs) != 3 {
+		t.Errorf("expected the outputs of 3 branches, got %v", result)
+	}
+
+	for name, runs := range map[string]int{"x": 1, "y": 2, "z": 1} {
+		if counter.runs[name] != runs {
+			t.Errorf("expected branch %s to run %d times, got %d", name, runs, counter.runs[name])
+		}
+	}
+}
+
+func TestCheckpointableRunnable_PendingCommandWrites(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := graph.NewFileCheckpointStoreWithDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	counter := &taskCounter{runs: map[string]int{}, flaky: "b"}
+
+	g := graph.NewCheckpointableMessageGraphWithConfig(graph.CheckpointConfig{Store: store})
+	g.SetStateMerger(func(_ context.Context, current interface{}, updates []interface{}) (interface{}, error) {
+		merged := map[string]interface{}{}
+		for _, state := range append([]interface{}{current}, updates...) {
+			for key, value := range state.(map[string]interface{}) {
+				merged[key] = value
+			}
+		}
+		return merged, nil
+	})
+	g.AddNode("start", counter.task("start"))
+	g.AddNode("a", func(ctx context.Context, state interface{}) (interface{}, error) {
+		if _, err := counter.task("a")(ctx, state); err != nil {
+			return nil, err
+		}
+		return &graph.Command{Update: map[string]interface{}{"a": "done"}, Goto: "x"}, nil
+	})
+	g.AddNode("b", counter.task("b"))
+	g.AddNode("x", func(ctx context.Context, state interface{}) (interface{}, error) {
+		update, _ := counter.task("x")(ctx, state)
+		merged := map[string]interface{}{"x": update.(map[string]interface{})["x"]}
+		for key, value := range state.(map[string]interface{}) {
+			merged[key] = value
+		}
+		return merged, nil
+	})
+	g.AddEdge("start", "a")
+	g.AddEdge("start", "b")
+	g.AddEdge("a", graph.END)
+	g.AddEdge("b", graph.END)
+	g.AddEdge("x", graph.END)
+	g.SetEntryPoint("start")
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	if _, err := runnable.InvokeWithConfig(ctx, map[string]interface{}{}, threadConfig("command")); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+
+	// The command of a is saved as its update and target, not as the command itself
+	state, err := runnable.GetState(ctx, "command")
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+	checkpoint, err := store.Load(ctx, state.CheckpointID)
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+	if len(checkpoint.PendingWrites) != 1 {
+		t.Fatalf("expected a pending write for a, got %+v", checkpoint.PendingWrites)
+	}
+	if write := checkpoint.PendingWrites[0]; !write.Command || len(write.Goto) != 1 || write.Goto[0] != "x" {
+		t.Errorf("expected the write of a to route to x, got %+v", write)
+	}
+
+	result, err := runnable.InvokeWithConfig(ctx, nil, threadConfig("command"))
+	if err != nil {
+		t.Fatalf("unexpected error on resume: %v", err)
+	}
+
+	final, _ := result.(map[string]interface{})
+	if len(final) != 4 || final["a"] != "done" || final["x"] != "done" {
+		t.Errorf("expected the updates of start, a, b and x only, got %v", result)
+	}
+
+	expected := map[string]int{"start": 1, "a": 1, "b": 2, "x": 1}
+	for name, runs := range expected {
+		if counter.runs[name] != runs {
+			t.Errorf("expected %s to run %d times, got %d", name, runs, counter.runs[name])
+		}
+	}
+}
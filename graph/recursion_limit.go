@@ -0,0 +1,54 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultRecursionLimit is the number of supersteps a run may take when its Config sets
+// no RecursionLimit
+const DefaultRecursionLimit = 10000
+
+// recentNodes is the number of nodes named by a RecursionLimitError
+const recentNodes = 10
+
+// ErrRecursionLimit is returned when a run takes more supersteps than its recursion limit
+// without reaching END, e.g. because a conditional edge keeps routing back to the same node.
+var ErrRecursionLimit = errors.New("recursion limit reached")
+
+// RecursionLimitError is returned when a run reaches its recursion limit.
+// errors.Is matches it against ErrRecursionLimit.
+type RecursionLimitError struct {
+	// Limit is the number of supersteps the run was allowed
+	Limit int
+
+	// Nodes are the last nodes that ran, oldest first
+	Nodes []string
+}
+
+func (e *RecursionLimitError) Error() string {
+	return fmt.Sprintf("%v: %d steps ran without reaching END (last nodes: %s)", ErrRecursionLimit, e.Limit, strings.Join(e.Nodes, ", "))
+}
+
+func (e *RecursionLimitError) Unwrap() error {
+	return ErrRecursionLimit
+}
+
+type remainingStepsKey struct{}
+
+// withRemainingSteps makes the number of steps left before the recursion limit available
+// to the nodes of a step
+func withRemainingSteps(ctx context.Context, remaining int) context.Context {
+	return context.WithValue(ctx, remainingStepsKey{}, remaining)
+}
+
+// RemainingSteps returns the number of supersteps the run of the calling node can still
+// take before it reaches its recursion limit, counting the node's own step. A node can
+// check it to wrap up, e.g. by routing to END, instead of failing with ErrRecursionLimit.
+// It returns false when called outside of a run.
+func RemainingSteps(ctx context.Context) (int, bool) {
+	remaining, ok := ctx.Value(remainingStepsKey{}).(int)
+	return remaining, ok
+}
@@ -0,0 +1,82 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+func TestRecursionLimit(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewMessageGraph()
+	g.AddNode("think", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state.(int) + 1, nil
+	})
+	g.AddNode("act", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	g.AddEdge("think", "act")
+	g.AddConditionalEdge("act", func(_ context.Context, _ interface{}) string {
+		return "think"
+	})
+	g.SetEntryPoint("think")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	_, err = runnable.InvokeWithConfig(context.Background(), 0, &graph.Config{RecursionLimit: 5})
+	if !errors.Is(err, graph.ErrRecursionLimit) {
+		t.Fatalf("expected the recursion limit to stop the run, got %v", err)
+	}
+
+	var limitErr *graph.RecursionLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("expected a *RecursionLimitError, got %T", err)
+	}
+	if limitErr.Limit != 5 || !reflect.DeepEqual(limitErr.Nodes, []string{"think", "act", "think", "act", "think"}) {
+		t.Errorf("unexpected recursion limit error: %+v", limitErr)
+	}
+}
+
+func TestRemainingSteps(t *testing.T) {
+	t.Parallel()
+
+	if _, ok := graph.RemainingSteps(context.Background()); ok {
+		t.Error("expected no remaining steps outside of a run")
+	}
+
+	var seen []int
+	g := graph.NewMessageGraph()
+	g.AddNode("agent", func(ctx context.Context, state interface{}) (interface{}, error) {
+		remaining, _ := graph.RemainingSteps(ctx)
+		seen = append(seen, remaining)
+		return state.(int) + 1, nil
+	})
+	g.AddConditionalEdge("agent", func(_ context.Context, _ interface{}) string {
+		// Wrap up while the run can still take the last step
+		if seen[len(seen)-1] <= 1 {
+			return graph.END
+		}
+		return "agent"
+	})
+	g.SetEntryPoint("agent")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	result, err := runnable.InvokeWithConfig(context.Background(), 0, &graph.Config{RecursionLimit: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 3 || !reflect.DeepEqual(seen, []int{3, 2, 1}) {
+		t.Errorf("expected 3 steps counting down, got %v after %v", result, seen)
+	}
+}
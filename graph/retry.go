@@ -2,9 +2,11 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
+	"sync"
 	"time"
 )
 
@@ -31,137 +33,169 @@ func DefaultRetryConfig() *RetryConfig {
 	}
 }
 
-// RetryNode wraps a node with retry logic
-type RetryNode struct {
-	node   Node
-	config *RetryConfig
+// RetryMiddleware returns a middleware that runs a node again when it fails with a
+// retryable error, waiting between attempts with exponential backoff. Interrupts are
+// returned as they are, without retrying.
+func RetryMiddleware(config *RetryConfig) Middleware {
+	return TypedRetryMiddleware[interface{}](config)
 }
 
-// NewRetryNode creates a new retry node
-func NewRetryNode(node Node, config *RetryConfig) *RetryNode {
+// TypedRetryMiddleware is RetryMiddleware for the nodes of a graph whose state has the
+// static type S
+func TypedRetryMiddleware[S any](config *RetryConfig) TypedMiddleware[S] {
 	if config == nil {
 		config = DefaultRetryConfig()
 	}
-	return &RetryNode{
-		node:   node,
-		config: config,
-	}
-}
 
-// Execute runs the node with retry logic
-func (rn *RetryNode) Execute(ctx context.Context, state interface{}) (interface{}, error) {
-	var lastErr error
-	delay := rn.config.InitialDelay
+	return func(next TypedNodeFunc[S]) TypedNodeFunc[S] {
+		return func(ctx context.Context, state S) (S, error) {
+			var zero S
+			var lastErr error
+			delay := config.InitialDelay
+
+			for attempt := 1; attempt <= config.MaxAttempts; attempt++ {
+				// Check context cancellation
+				select {
+				case <-ctx.Done():
+					return zero, fmt.Errorf("retry cancelled: %w", ctx.Err())
+				default:
+				}
 
-	for attempt := 1; attempt <= rn.config.MaxAttempts; attempt++ {
-		// Check context cancellation
-		select {
-		case <-ctx.Done():
-			return nil, fmt.Errorf("retry cancelled: %w", ctx.Err())
-		default:
-		}
+				// Execute the node
+				result, err := next(ctx, state)
+				if err == nil {
+					return result, nil
+				}
 
-		// Execute the node
-		result, err := rn.node.Function(ctx, state)
-		if err == nil {
-			return result, nil
-		}
+				// A node pausing for input must not run again before the run is resumed
+				if errors.Is(err, ErrGraphInterrupted) {
+					return zero, err
+				}
 
-		lastErr = err
+				lastErr = err
 
-		// Check if error is retryable
-		if rn.config.RetryableErrors != nil && !rn.config.RetryableErrors(err) {
-			return nil, fmt.Errorf("non-retryable error in %s: %w", rn.node.Name, err)
-		}
+				// Check if error is retryable
+				if config.RetryableErrors != nil && !config.RetryableErrors(err) {
+					return zero, fmt.Errorf("non-retryable error in %s: %w", nodeName(ctx), err)
+				}
 
-		// Don't sleep after the last attempt
-		if attempt < rn.config.MaxAttempts {
-			// Sleep with exponential backoff
-			select {
-			case <-time.After(delay):
-				// Calculate next delay with backoff
-				delay = time.Duration(float64(delay) * rn.config.BackoffFactor)
-				if delay > rn.config.MaxDelay {
-					delay = rn.config.MaxDelay
+				// Don't sleep after the last attempt
+				if attempt < config.MaxAttempts {
+					// Sleep with exponential backoff
+					select {
+					case <-time.After(delay):
+						// Calculate next delay with backoff
+						delay = time.Duration(float64(delay) * config.BackoffFactor)
+						if delay > config.MaxDelay {
+							delay = config.MaxDelay
+						}
+					case <-ctx.Done():
+						return zero, fmt.Errorf("retry cancelled during backoff: %w", ctx.Err())
+					}
 				}
-			case <-ctx.Done():
-				return nil, fmt.Errorf("retry cancelled during backoff: %w", ctx.Err())
 			}
+
+			return zero, fmt.Errorf("max retries (%d) exceeded for %s: %w", config.MaxAttempts, nodeName(ctx), lastErr)
 		}
 	}
+}
+
+// RetryNode wraps a node with retry logic
+type RetryNode struct {
+	node    Node
+	execute NodeFunc
+}
+
+// NewRetryNode creates a new retry node
+func NewRetryNode(node Node, config *RetryConfig) *RetryNode {
+	return &RetryNode{
+		node:    node,
+		execute: chain(node.Name, node.Function, []Middleware{RetryMiddleware(config)}),
+	}
+}
 
-	return nil, fmt.Errorf("max retries (%d) exceeded for %s: %w",
-		rn.config.MaxAttempts, rn.node.Name, lastErr)
+// Execute runs the node with retry logic
+func (rn *RetryNode) Execute(ctx context.Context, state interface{}) (interface{}, error) {
+	return rn.execute(ctx, state)
 }
 
-// AddNodeWithRetry adds a node with retry logic
+// AddNodeWithRetry adds a node with retry logic.
+// It is a shorthand for AddNode with WithMiddleware(RetryMiddleware(config)).
 func (g *MessageGraph) AddNodeWithRetry(
 	name string,
 	fn func(context.Context, interface{}) (interface{}, error),
 	config *RetryConfig,
 ) {
-	node := Node{
-		Name:     name,
-		Function: fn,
+	g.AddNode(name, fn, WithMiddleware(RetryMiddleware(config)))
+}
+
+// TimeoutMiddleware returns a middleware that fails a node which runs longer than the
+// given timeout. The context of the node is cancelled when the timeout elapses.
+func TimeoutMiddleware(timeout time.Duration) Middleware {
+	return TypedTimeoutMiddleware[interface{}](timeout)
+}
+
+// TypedTimeoutMiddleware is TimeoutMiddleware for the nodes of a graph whose state has the
+// static type S
+func TypedTimeoutMiddleware[S any](timeout time.Duration) TypedMiddleware[S] {
+	return func(next TypedNodeFunc[S]) TypedNodeFunc[S] {
+		return func(ctx context.Context, state S) (S, error) {
+			// Create a timeout context
+			timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			// Channel for result
+			type result struct {
+				value S
+				err   error
+			}
+			resultChan := make(chan result, 1)
+
+			// Execute in goroutine
+			go func() {
+				value, err := next(timeoutCtx, state)
+				resultChan <- result{value: value, err: err}
+			}()
+
+			// Wait for result or timeout
+			select {
+			case res := <-resultChan:
+				return res.value, res.err
+			case <-timeoutCtx.Done():
+				var zero S
+				return zero, fmt.Errorf("node %s timed out after %v", nodeName(ctx), timeout)
+			}
+		}
 	}
-	retryNode := NewRetryNode(node, config)
-	g.AddNode(name, retryNode.Execute)
 }
 
 // TimeoutNode wraps a node with timeout logic
 type TimeoutNode struct {
 	node    Node
-	timeout time.Duration
+	execute NodeFunc
 }
 
 // NewTimeoutNode creates a new timeout node
 func NewTimeoutNode(node Node, timeout time.Duration) *TimeoutNode {
 	return &TimeoutNode{
 		node:    node,
-		timeout: timeout,
+		execute: chain(node.Name, node.Function, []Middleware{TimeoutMiddleware(timeout)}),
 	}
 }
 
 // Execute runs the node with timeout
 func (tn *TimeoutNode) Execute(ctx context.Context, state interface{}) (interface{}, error) {
-	// Create a timeout context
-	timeoutCtx, cancel := context.WithTimeout(ctx, tn.timeout)
-	defer cancel()
-
-	// Channel for result
-	type result struct {
-		value interface{}
-		err   error
-	}
-	resultChan := make(chan result, 1)
-
-	// Execute in goroutine
-	go func() {
-		value, err := tn.node.Function(timeoutCtx, state)
-		resultChan <- result{value: value, err: err}
-	}()
-
-	// Wait for result or timeout
-	select {
-	case res := <-resultChan:
-		return res.value, res.err
-	case <-timeoutCtx.Done():
-		return nil, fmt.Errorf("node %s timed out after %v", tn.node.Name, tn.timeout)
-	}
+	return tn.execute(ctx, state)
 }
 
-// AddNodeWithTimeout adds a node with timeout
+// AddNodeWithTimeout adds a node with timeout.
+// It is a shorthand for AddNode with WithMiddleware(TimeoutMiddleware(timeout)).
 func (g *MessageGraph) AddNodeWithTimeout(
 	name string,
 	fn func(context.Context, interface{}) (interface{}, error),
 	timeout time.Duration,
 ) {
-	node := Node{
-		Name:     name,
-		Function: fn,
-	}
-	timeoutNode := NewTimeoutNode(node, timeout)
-	g.AddNode(name, timeoutNode.Execute)
+	g.AddNode(name, fn, WithMiddleware(TimeoutMiddleware(timeout)))
 }
 
 // CircuitBreakerConfig configures circuit breaker behavior
@@ -190,6 +224,7 @@ type CircuitBreaker struct {
 	successes       int
 	lastFailureTime time.Time
 	halfOpenCalls   int
+	mutex           sync.Mutex
 }
 
 // NewCircuitBreaker creates a new circuit breaker
@@ -201,33 +236,66 @@ func NewCircuitBreaker(node Node, config CircuitBreakerConfig) *CircuitBreaker {
 	}
 }
 
+// CircuitBreakerMiddleware returns a middleware that stops running a node after it failed
+// repeatedly, until the configured timeout elapses. Every node wrapped by the middleware
+// has a circuit breaker of its own. Interrupts and cancelled runs are neither failures
+// nor successes.
+func CircuitBreakerMiddleware(config CircuitBreakerConfig) Middleware {
+	return TypedCircuitBreakerMiddleware[interface{}](config)
+}
+
+// TypedCircuitBreakerMiddleware is CircuitBreakerMiddleware for the nodes of a graph whose
+// state has the static type S
+func TypedCircuitBreakerMiddleware[S any](config CircuitBreakerConfig) TypedMiddleware[S] {
+	return func(next TypedNodeFunc[S]) TypedNodeFunc[S] {
+		cb := NewCircuitBreaker(Node{}, config)
+		return func(ctx context.Context, state S) (S, error) {
+			var zero S
+			name := nodeName(ctx)
+			if err := cb.acquire(name); err != nil {
+				return zero, err
+			}
+
+			result, err := next(ctx, state)
+			if err := cb.record(ctx, name, err); err != nil {
+				return zero, err
+			}
+			return result, nil
+		}
+	}
+}
+
 // Execute runs the node with circuit breaker logic
 func (cb *CircuitBreaker) Execute(ctx context.Context, state interface{}) (interface{}, error) {
-	// Check circuit state
-	switch cb.state {
-	case CircuitClosed:
-		// Circuit is closed, proceed normally
-	case CircuitOpen:
-		// Check if enough time has passed to try again
-		if time.Since(cb.lastFailureTime) > cb.config.Timeout {
-			cb.state = CircuitHalfOpen
-			cb.halfOpenCalls = 0
-		} else {
-			return nil, fmt.Errorf("circuit breaker open for %s", cb.node.Name)
-		}
-	case CircuitHalfOpen:
-		// Check if we've made too many calls in half-open state
-		if cb.halfOpenCalls >= cb.config.HalfOpenMaxCalls {
-			cb.state = CircuitOpen
-			return nil, fmt.Errorf("circuit breaker half-open limit reached for %s", cb.node.Name)
-		}
-		cb.halfOpenCalls++
+	return cb.execute(ctx, cb.node.Name, state)
+}
+
+// execute runs the node function with circuit breaker logic, naming the node in errors
+func (cb *CircuitBreaker) execute(ctx context.Context, name string, state interface{}) (interface{}, error) {
+	if err := cb.acquire(name); err != nil {
+		return nil, err
 	}
 
 	// Execute the node
 	result, err := cb.node.Function(ctx, state)
+	if err := cb.record(ctx, name, err); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// record updates the circuit with the outcome of a call to the named node, returning the
+// error the call should fail with
+func (cb *CircuitBreaker) record(ctx context.Context, name string, err error) error {
+	// A node pausing for input, or stopped with its run, says nothing about its health
+	if err != nil && (errors.Is(err, ErrGraphInterrupted) || errors.Is(err, context.Canceled) || ctx.Err() != nil) {
+		return err
+	}
 
 	// Update circuit breaker state based on result
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
 	if err != nil {
 		cb.failures++
 		cb.successes = 0
@@ -237,7 +305,7 @@ func (cb *CircuitBreaker) Execute(ctx context.Context, state interface{}) (inter
 			cb.state = CircuitOpen
 		}
 
-		return nil, fmt.Errorf("circuit breaker error in %s: %w", cb.node.Name, err)
+		return fmt.Errorf("circuit breaker error in %s: %w", name, err)
 	}
 
 	// Success
@@ -248,21 +316,46 @@ func (cb *CircuitBreaker) Execute(ctx context.Context, state interface{}) (inter
 		cb.state = CircuitClosed
 	}
 
-	return result, nil
+	return nil
 }
 
-// AddNodeWithCircuitBreaker adds a node with circuit breaker
+// acquire checks whether the circuit lets a call to the named node through
+func (cb *CircuitBreaker) acquire(name string) error {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	// Check circuit state
+	switch cb.state {
+	case CircuitClosed:
+		// Circuit is closed, proceed normally
+	case CircuitOpen:
+		// Check if enough time has passed to try again
+		if time.Since(cb.lastFailureTime) > cb.config.Timeout {
+			cb.state = CircuitHalfOpen
+			cb.halfOpenCalls = 0
+		} else {
+			return fmt.Errorf("circuit breaker open for %s", name)
+		}
+	case CircuitHalfOpen:
+		// Check if we've made too many calls in half-open state
+		if cb.halfOpenCalls >= cb.config.HalfOpenMaxCalls {
+			cb.state = CircuitOpen
+			return fmt.Errorf("circuit breaker half-open limit reached for %s", name)
+		}
+		cb.halfOpenCalls++
+	}
+
+	return nil
+}
+
+// AddNodeWithCircuitBreaker adds a node with circuit breaker.
+// It is a shorthand for AddNode with WithMiddleware(CircuitBreakerMiddleware(config)).
 func (g *MessageGraph) AddNodeWithCircuitBreaker(
 	name string,
 	fn func(context.Context, interface{}) (interface{}, error),
 	config CircuitBreakerConfig,
 ) {
-	node := Node{
-		Name:     name,
-		Function: fn,
-	}
-	cb := NewCircuitBreaker(node, config)
-	g.AddNode(name, cb.Execute)
+	g.AddNode(name, fn, WithMiddleware(CircuitBreakerMiddleware(config)))
 }
 
 // RateLimiter implements rate limiting for nodes
@@ -271,6 +364,7 @@ type RateLimiter struct {
 	maxCalls int
 	window   time.Duration
 	calls    []time.Time
+	mutex    sync.Mutex
 }
 
 // NewRateLimiter creates a new rate limiter
@@ -283,8 +377,47 @@ func NewRateLimiter(node Node, maxCalls int, window time.Duration) *RateLimiter
 	}
 }
 
+// RateLimitMiddleware returns a middleware that fails the calls of a node beyond maxCalls
+// within the sliding window. Every node wrapped by the middleware has a limit of its own.
+func RateLimitMiddleware(maxCalls int, window time.Duration) Middleware {
+	return TypedRateLimitMiddleware[interface{}](maxCalls, window)
+}
+
+// TypedRateLimitMiddleware is RateLimitMiddleware for the nodes of a graph whose state has
+// the static type S
+func TypedRateLimitMiddleware[S any](maxCalls int, window time.Duration) TypedMiddleware[S] {
+	return func(next TypedNodeFunc[S]) TypedNodeFunc[S] {
+		rl := NewRateLimiter(Node{}, maxCalls, window)
+		return func(ctx context.Context, state S) (S, error) {
+			if err := rl.acquire(nodeName(ctx)); err != nil {
+				var zero S
+				return zero, err
+			}
+			return next(ctx, state)
+		}
+	}
+}
+
 // Execute runs the node with rate limiting
 func (rl *RateLimiter) Execute(ctx context.Context, state interface{}) (interface{}, error) {
+	return rl.execute(ctx, rl.node.Name, state)
+}
+
+// execute runs the node function with rate limiting, naming the node in errors
+func (rl *RateLimiter) execute(ctx context.Context, name string, state interface{}) (interface{}, error) {
+	if err := rl.acquire(name); err != nil {
+		return nil, err
+	}
+
+	// Execute the node
+	return rl.node.Function(ctx, state)
+}
+
+// acquire records a call to the named node, unless the limit of the window is reached
+func (rl *RateLimiter) acquire(name string) error {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
 	now := time.Now()
 
 	// Remove old calls outside the window
@@ -301,29 +434,23 @@ func (rl *RateLimiter) Execute(ctx context.Context, state interface{}) (interfac
 		// Calculate when we can make the next call
 		oldestCall := rl.calls[0]
 		waitTime := rl.window - now.Sub(oldestCall)
-		return nil, fmt.Errorf("rate limit exceeded for %s, retry after %v", rl.node.Name, waitTime)
+		return fmt.Errorf("rate limit exceeded for %s, retry after %v", name, waitTime)
 	}
 
 	// Record this call
 	rl.calls = append(rl.calls, now)
-
-	// Execute the node
-	return rl.node.Function(ctx, state)
+	return nil
 }
 
-// AddNodeWithRateLimit adds a node with rate limiting
+// AddNodeWithRateLimit adds a node with rate limiting.
+// It is a shorthand for AddNode with WithMiddleware(RateLimitMiddleware(maxCalls, window)).
 func (g *MessageGraph) AddNodeWithRateLimit(
 	name string,
 	fn func(context.Context, interface{}) (interface{}, error),
 	maxCalls int,
 	window time.Duration,
 ) {
-	node := Node{
-		Name:     name,
-		Function: fn,
-	}
-	rl := NewRateLimiter(node, maxCalls, window)
-	g.AddNode(name, rl.Execute)
+	g.AddNode(name, fn, WithMiddleware(RateLimitMiddleware(maxCalls, window)))
 }
 
 // ExponentialBackoffRetry implements exponential backoff with jitter
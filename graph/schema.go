@@ -0,0 +1,217 @@
+package graph
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Reducer combines the current value of a state key with an update returned by a node.
+// The current value is nil when the key has not been written yet.
+type Reducer func(current, update interface{}) (interface{}, error)
+
+// StateSchema describes how the updates returned by nodes are folded into the graph state.
+// When a schema is set, nodes return partial updates instead of the complete state.
+type StateSchema[S any] interface {
+	// Init returns the state a run starts from, before the input is applied
+	Init() S
+
+	// Update folds an update into the current state and returns the new state
+	Update(current, update S) (S, error)
+}
+
+// MapSchema is a StateSchema for map[string]interface{} states in which every key
+// (channel) has its own reducer. Keys without a registered reducer are overwritten.
+type MapSchema struct {
+	reducers map[string]Reducer
+}
+
+// NewMapSchema creates a new map schema with no channels
+func NewMapSchema() *MapSchema {
+	return &MapSchema{
+		reducers: make(map[string]Reducer),
+	}
+}
+
+// AddChannel registers a state key and the reducer used to apply updates to it
+func (s *MapSchema) AddChannel(key string, reducer Reducer) *MapSchema {
+	if reducer == nil {
+		reducer = OverwriteReducer
+	}
+	s.reducers[key] = reducer
+	return s
+}
+
+// Init implements StateSchema and returns an empty state
+func (s *MapSchema) Init() map[string]interface{} {
+	return make(map[string]interface{})
+}
+
+// Update implements StateSchema by applying each key of the update through its reducer.
+// The current state is not modified; a new map is returned.
+func (s *MapSchema) Update(current, update map[string]interface{}) (map[string]interface{}, error) {
+	next := make(map[string]interface{}, len(current)+len(update))
+	for key, value := range current {
+		next[key] = value
+	}
+
+	for key, value := range update {
+		reducer, ok := s.reducers[key]
+		if !ok {
+			reducer = OverwriteReducer
+		}
+
+		reduced, err := reducer(next[key], value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reduce state key %q: %w", key, err)
+		}
+		next[key] = reduced
+	}
+
+	return next, nil
+}
+
+// untypedMapSchema adapts a map schema to graphs whose state is interface{}
+type untypedMapSchema struct {
+	schema StateSchema[map[string]interface{}]
+}
+
+// Init implements StateSchema
+func (u untypedMapSchema) Init() interface{} {
+	return u.schema.Init()
+}
+
+// Update implements StateSchema. A nil update leaves the state unchanged.
+func (u untypedMapSchema) Update(current, update interface{}) (interface{}, error) {
+	if update == nil {
+		return current, nil
+	}
+
+	updateMap, ok := update.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("state update must be map[string]interface{}, got %T", update)
+	}
+
+	var currentMap map[string]interface{}
+	if current != nil {
+		if currentMap, ok = current.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("state must be map[string]interface{}, got %T", current)
+		}
+	}
+
+	return u.schema.Update(currentMap, updateMap)
+}
+
+// OverwriteReducer replaces the current value with the update
+func OverwriteReducer(_, update interface{}) (interface{}, error) {
+	return update, nil
+}
+
+// AppendReducer appends the update to the current slice. The update may be a slice of
+// the same type or a single element. A nil current value starts a new slice, and a nil
+// update leaves the current value unchanged.
+func AppendReducer(current, update interface{}) (interface{}, error) {
+	if current == nil {
+		if update == nil {
+			return nil, nil
+		}
+		updateValue := reflect.ValueOf(update)
+		if updateValue.Kind() == reflect.Slice {
+			return reflect.AppendSlice(reflect.MakeSlice(updateValue.Type(), 0, updateValue.Len()), updateValue).Interface(), nil
+		}
+		return []interface{}{update}, nil
+	}
+
+	currentValue := reflect.ValueOf(current)
+	if currentValue.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("append reducer requires a slice, got %T", current)
+	}
+
+	// Copy so that earlier states sharing the backing array are never modified
+	merged := reflect.AppendSlice(reflect.MakeSlice(currentValue.Type(), 0, currentValue.Len()), currentValue)
+
+	if update == nil {
+		return merged.Interface(), nil
+	}
+
+	updateValue := reflect.ValueOf(update)
+	elemType := currentValue.Type().Elem()
+
+	switch {
+	case updateValue.Type().AssignableTo(currentValue.Type()):
+		merged = reflect.AppendSlice(merged, updateValue)
+	case updateValue.Kind() == reflect.Slice && updateValue.Type().Elem().AssignableTo(elemType):
+		for i := 0; i < updateValue.Len(); i++ {
+			merged = reflect.Append(merged, updateValue.Index(i))
+		}
+	case updateValue.Type().AssignableTo(elemType):
+		merged = reflect.Append(merged, updateValue)
+	default:
+		return nil, fmt.Errorf("cannot append %T to %T", update, current)
+	}
+
+	return merged.Interface(), nil
+}
+
+// SumReducer adds a numeric update to the current value. Both values must have the same type.
+// A nil update leaves the current value unchanged.
+func SumReducer(current, update interface{}) (interface{}, error) {
+	if update == nil {
+		return current, nil
+	}
+	if current == nil {
+		return update, nil
+	}
+
+	currentValue := reflect.ValueOf(current)
+	updateValue := reflect.ValueOf(update)
+	if currentValue.Type() != updateValue.Type() {
+		return nil, fmt.Errorf("cannot add %T to %T", update, current)
+	}
+
+	sum := reflect.New(currentValue.Type()).Elem()
+	switch currentValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		sum.SetInt(currentValue.Int() + updateValue.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		sum.SetUint(currentValue.Uint() + updateValue.Uint())
+	case reflect.Float32, reflect.Float64:
+		sum.SetFloat(currentValue.Float() + updateValue.Float())
+	default:
+		return nil, fmt.Errorf("sum reducer requires a numeric value, got %T", current)
+	}
+
+	return sum.Interface(), nil
+}
+
+// MergeMapReducer merges the keys of the update map into the current map.
+// Both values must be maps of the same type; keys in the update win. A nil update leaves
+// the current value unchanged.
+func MergeMapReducer(current, update interface{}) (interface{}, error) {
+	if update == nil {
+		return current, nil
+	}
+
+	updateValue := reflect.ValueOf(update)
+	if updateValue.Kind() != reflect.Map {
+		return nil, fmt.Errorf("merge map reducer requires a map, got %T", update)
+	}
+
+	if current == nil {
+		current = reflect.MakeMap(updateValue.Type()).Interface()
+	}
+
+	currentValue := reflect.ValueOf(current)
+	if currentValue.Type() != updateValue.Type() {
+		return nil, fmt.Errorf("cannot merge %T into %T", update, current)
+	}
+
+	merged := reflect.MakeMapWithSize(currentValue.Type(), currentValue.Len()+updateValue.Len())
+	for _, key := range currentValue.MapKeys() {
+		merged.SetMapIndex(key, currentValue.MapIndex(key))
+	}
+	for _, key := range updateValue.MapKeys() {
+		merged.SetMapIndex(key, updateValue.MapIndex(key))
+	}
+
+	return merged.Interface(), nil
+}
@@ -0,0 +1,323 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+func TestReducers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		reducer  graph.Reducer
+		current  interface{}
+		update   interface{}
+		expected interface{}
+		wantErr  bool
+	}{
+		{
+			name:     "Overwrite replaces value",
+			reducer:  graph.OverwriteReducer,
+			current:  "old",
+			update:   "new",
+			expected: "new",
+		},
+		{
+			name:     "Append slice to nil",
+			reducer:  graph.AppendReducer,
+			current:  nil,
+			update:   []string{"a"},
+			expected: []string{"a"},
+		},
+		{
+			name:     "Append slice to slice",
+			reducer:  graph.AppendReducer,
+			current:  []string{"a"},
+			update:   []string{"b", "c"},
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "Append single element",
+			reducer:  graph.AppendReducer,
+			current:  []int{1},
+			update:   2,
+			expected: []int{1, 2},
+		},
+		{
+			name:     "Append typed slice to interface slice",
+			reducer:  graph.AppendReducer,
+			current:  []interface{}{"a"},
+			update:   []string{"b"},
+			expected: []interface{}{"a", "b"},
+		},
+		{
+			name:    "Append incompatible type",
+			reducer: graph.AppendReducer,
+			current: []int{1},
+			update:  "x",
+			wantErr: true,
+		},
+		{
+			name:     "Sum integers",
+			reducer:  graph.SumReducer,
+			current:  2,
+			update:   3,
+			expected: 5,
+		},
+		{
+			name:     "Sum floats from nil",
+			reducer:  graph.SumReducer,
+			current:  nil,
+			update:   1.5,
+			expected: 1.5,
+		},
+		{
+			name:     "Sum nil update",
+			reducer:  graph.SumReducer,
+			current:  1,
+			update:   nil,
+			expected: 1,
+		},
+		{
+			name:     "Append nil to nil",
+			reducer:  graph.AppendReducer,
+			current:  nil,
+			update:   nil,
+			expected: nil,
+		},
+		{
+			name:     "Merge nil update",
+			reducer:  graph.MergeMapReducer,
+			current:  map[string]int{"a": 1},
+			update:   nil,
+			expected: map[string]int{"a": 1},
+		},
+		{
+			name:    "Sum mismatched types",
+			reducer: graph.SumReducer,
+			current: 1,
+			update:  1.5,
+			wantErr: true,
+		},
+		{
+			name:     "Merge maps",
+			reducer:  graph.MergeMapReducer,
+			current:  map[string]int{"a": 1, "b": 2},
+			update:   map[string]int{"b": 3, "c": 4},
+			expected: map[string]int{"a": 1, "b": 3, "c": 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := tt.reducer(tt.current, tt.update)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got result %v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fmt.Sprint(result) != fmt.Sprint(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMapSchema_DoesNotModifyCurrentState(t *testing.T) {
+	t.Parallel()
+
+	schema := graph.NewMapSchema().AddChannel("items", graph.AppendReducer)
+
+	items := make([]string, 1, 4)
+	items[0] = "a"
+	current := map[string]interface{}{"items": items}
+
+	first, err := schema.Update(current, map[string]interface{}{"items": []string{"b"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := schema.Update(current, map[string]interface{}{"items": []string{"c"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fmt.Sprint(current["items"]) != "[a]" {
+		t.Errorf("current state was modified: %v", current["items"])
+	}
+	if fmt.Sprint(first["items"]) != "[a b]" || fmt.Sprint(second["items"]) != "[a c]" {
+		t.Errorf("updates share state: %v, %v", first["items"], second["items"])
+	}
+}
+
+func TestStateGraph_SchemaMergesParallelBranches(t *testing.T) {
+	t.Parallel()
+
+	schema := graph.NewMapSchema().
+		AddChannel("results", graph.AppendReducer).
+		AddChannel("calls", graph.SumReducer)
+
+	g := graph.NewStateGraph()
+	g.SetSchema(schema)
+
+	g.AddNode("start", func(_ context.Context, _ interface{}) (interface{}, error) {
+		return map[string]interface{}{"calls": 1}, nil
+	})
+	for _, name := range []string{"search", "lookup"} {
+		name := name
+		g.AddNode(name, func(_ context.Context, _ interface{}) (interface{}, error) {
+			return map[string]interface{}{
+				"results": []string{name},
+				"calls":   1,
+			}, nil
+		})
+		g.AddEdge("start", name)
+		g.AddEdge(name, "summarize")
+	}
+	g.AddNode("summarize", func(_ context.Context, state interface{}) (interface{}, error) {
+		results := state.(map[string]interface{})["results"].([]string)
+		return map[string]interface{}{
+			"summary": fmt.Sprintf("%d results", len(results)),
+			"calls":   1,
+		}, nil
+	})
+	g.AddEdge("summarize", graph.END)
+	g.SetEntryPoint("start")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), map[string]interface{}{
+		"query":   "langgraph",
+		"results": []string{},
+	})
+	if err != nil {
+		t.Fatalf("unexpected invoke error: %v", err)
+	}
+
+	state := result.(map[string]interface{})
+	if state["query"] != "langgraph" {
+		t.Errorf("expected input key to be preserved, got %v", state["query"])
+	}
+	if state["calls"] != 4 {
+		t.Errorf("expected 4 calls, got %v", state["calls"])
+	}
+	if state["summary"] != "2 results" {
+		t.Errorf("expected summary of 2 results, got %v", state["summary"])
+	}
+	if fmt.Sprint(state["results"]) != "[search lookup]" {
+		t.Errorf("expected results in edge order, got %v", state["results"])
+	}
+}
+
+func TestStateGraph_SchemaRejectsNonMapUpdate(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewStateGraph()
+	g.SetSchema(graph.NewMapSchema())
+	g.AddNode("bad", func(_ context.Context, _ interface{}) (interface{}, error) {
+		return "not a map", nil
+	})
+	g.AddEdge("bad", graph.END)
+	g.SetEntryPoint("bad")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	if _, err := runnable.Invoke(context.Background(), nil); err == nil {
+		t.Error("expected error for non-map update")
+	}
+}
+
+func TestStateGraph_AddParallelNodesWithSchema(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewStateGraph()
+	g.AddParallelNodes("workers", map[string]func(context.Context, interface{}) (interface{}, error){
+		"double": func(_ context.Context, state interface{}) (interface{}, error) {
+			n := state.(map[string]interface{})["n"].(int)
+			return map[string]interface{}{"outputs": []int{n * 2}}, nil
+		},
+		"square": func(_ context.Context, state interface{}) (interface{}, error) {
+			n := state.(map[string]interface{})["n"].(int)
+			return map[string]interface{}{"outputs": []int{n * n}}, nil
+		},
+		"fail": func(_ context.Context, state interface{}) (interface{}, error) {
+			if state.(map[string]interface{})["n"].(int) < 0 {
+				return nil, errors.New("negative input")
+			}
+			return nil, nil
+		},
+	})
+	g.AddEdge("workers", graph.END)
+	g.SetEntryPoint("workers")
+	g.SetSchema(graph.NewMapSchema().AddChannel("outputs", graph.AppendReducer))
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), map[string]interface{}{"n": 3})
+	if err != nil {
+		t.Fatalf("unexpected invoke error: %v", err)
+	}
+
+	outputs := result.(map[string]interface{})["outputs"].([]int)
+	sort.Ints(outputs)
+	if fmt.Sprint(outputs) != "[6 9]" {
+		t.Errorf("expected merged outputs [6 9], got %v", outputs)
+	}
+
+	if _, err := runnable.Invoke(context.Background(), map[string]interface{}{"n": -1}); err == nil {
+		t.Error("expected error from failing parallel node")
+	}
+}
+
+func TestStateGraph_AddMapReduceNodeWithSchema(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewStateGraph()
+	g.AddMapReduceNode("count", map[string]func(context.Context, interface{}) (interface{}, error){
+		"words": func(_ context.Context, state interface{}) (interface{}, error) {
+			text := state.(map[string]interface{})["text"].(string)
+			return map[string]interface{}{"total": len(strings.Fields(text))}, nil
+		},
+		"lines": func(_ context.Context, state interface{}) (interface{}, error) {
+			text := state.(map[string]interface{})["text"].(string)
+			return map[string]interface{}{"total": len(strings.Split(text, "\n"))}, nil
+		},
+	}, nil)
+	g.AddEdge("count", graph.END)
+	g.SetEntryPoint("count")
+	g.SetSchema(graph.NewMapSchema().AddChannel("total", graph.SumReducer))
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), map[string]interface{}{"text": "a b\nc", "total": 0})
+	if err != nil {
+		t.Fatalf("unexpected invoke error: %v", err)
+	}
+	if total := result.(map[string]interface{})["total"]; total != 5 {
+		t.Errorf("expected the map results to be summed into 5, got %v", total)
+	}
+}
@@ -0,0 +1,69 @@
+package graph
+
+import "encoding/json"
+
+// Send schedules a node to run in the next step with the given state as its input,
+// instead of the state of the graph. Sending to a node several times runs it once per
+// Send, e.g. once for each document retrieved by the current step, and the updates of
+// all of them are merged into the state like those of any other nodes of the step.
+//
+// Sends are returned by the functions of send edges, added with AddSendEdge, or set as
+// the Goto of a Command.
+type Send struct {
+	// Node is the node to run
+	Node string `json:"node"`
+
+	// State is the input of the node
+	State interface{} `json:"state"`
+}
+
+// sendRecord is the form in which checkpoint stores persist a Send, with its state
+// encoded by the store's serializer like a checkpoint state
+type sendRecord struct {
+	Node         string          `json:"node"`
+	State        json.RawMessage `json:"state,omitempty"`
+	EncodedState []byte          `json:"encoded_state,omitempty"`
+}
+
+// encodeSends encodes sends as records with the given serializer
+func encodeSends(serializer Serializer, sends []Send) ([]sendRecord, error) {
+	if len(sends) == 0 {
+		return nil, nil
+	}
+
+	records := make([]sendRecord, len(sends))
+	for i, send := range sends {
+		state, encodedState, err := encodeState(serializer, send.State)
+		if err != nil {
+			return nil, err
+		}
+		records[i] = sendRecord{Node: send.Node, State: state, EncodedState: encodedState}
+	}
+	return records, nil
+}
+
+// decodeSends decodes records encoded by encodeSends
+func decodeSends(serializer Serializer, records []sendRecord) ([]Send, error) {
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	sends := make([]Send, len(records))
+	for i, record := range records {
+		state, err := decodeState(serializer, record.State, record.EncodedState)
+		if err != nil {
+			return nil, err
+		}
+		sends[i] = Send{Node: record.Node, State: state}
+	}
+	return sends, nil
+}
+
+// sendNodes returns the names of the nodes the sends run
+func sendNodes(sends []Send) []string {
+	nodes := make([]string, len(sends))
+	for i, send := range sends {
+		nodes[i] = send.Node
+	}
+	return nodes
+}
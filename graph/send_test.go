@@ -0,0 +1,183 @@
+package graph_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+func TestSendEdge_MapReduce(t *testing.T) {
+	t.Parallel()
+
+	var running, peak, combined int32
+
+	g := graph.NewStateGraph()
+	g.SetSchema(graph.NewMapSchema().AddChannel("summaries", graph.AppendReducer))
+	g.AddNode("retrieve", func(_ context.Context, _ interface{}) (interface{}, error) {
+		docs := []interface{}{}
+		for i := 0; i < 8; i++ {
+			docs = append(docs, fmt.Sprintf("doc%d", i))
+		}
+		return map[string]interface{}{"docs": docs}, nil
+	})
+	g.AddNode("summarize", func(_ context.Context, state interface{}) (interface{}, error) {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			highest := atomic.LoadInt32(&peak)
+			if current <= highest || atomic.CompareAndSwapInt32(&peak, highest, current) {
+				break
+			}
+		}
+
+		doc := state.(map[string]interface{})["doc"].(string)
+		return map[string]interface{}{"summaries": []interface{}{"summary of " + doc}}, nil
+	})
+	g.AddNode("combine", func(_ context.Context, state interface{}) (interface{}, error) {
+		atomic.AddInt32(&combined, 1)
+		return map[string]interface{}{"done": true}, nil
+	})
+	g.AddSendEdge("retrieve", func(_ context.Context, state interface{}) []graph.Send {
+		var sends []graph.Send
+		for _, doc := range state.(map[string]interface{})["docs"].([]interface{}) {
+			sends = append(sends, graph.Send{Node: "summarize", State: map[string]interface{}{"doc": doc}})
+		}
+		return sends
+	})
+	g.AddEdge("summarize", "combine")
+	g.AddEdge("combine", graph.END)
+	g.SetEntryPoint("retrieve")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	result, err := runnable.InvokeWithConfig(context.Background(), map[string]interface{}{}, &graph.Config{MaxConcurrency: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	state := result.(map[string]interface{})
+	summaries := state["summaries"].([]interface{})
+	if len(summaries) != 8 || summaries[0] != "summary of doc0" || state["done"] != true {
+		t.Errorf("expected 8 summaries in send order, got %v", state)
+	}
+	if combined != 1 {
+		t.Errorf("expected combine to run once, ran %d times", combined)
+	}
+	if peak > 2 {
+		t.Errorf("expected at most 2 concurrent summaries, got %d", peak)
+	}
+}
+
+func TestCommand_GotoSend(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewStateGraph()
+	g.SetSchema(graph.NewMapSchema().AddChannel("greetings", graph.AppendReducer))
+	g.AddNode("plan", func(_ context.Context, _ interface{}) (interface{}, error) {
+		return graph.Command{
+			Update: map[string]interface{}{"planned": true},
+			Goto: []graph.Send{
+				{Node: "greet", State: map[string]interface{}{"name": "ada"}},
+				{Node: "greet", State: map[string]interface{}{"name": "grace"}},
+			},
+		}, nil
+	})
+	g.AddNode("greet", func(_ context.Context, state interface{}) (interface{}, error) {
+		return map[string]interface{}{"greetings": []interface{}{"hello " + state.(map[string]interface{})["name"].(string)}}, nil
+	})
+	g.AddEdge("greet", graph.END)
+	g.SetEntryPoint("plan")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	state := result.(map[string]interface{})
+	if state["planned"] != true || !reflect.DeepEqual(state["greetings"], []interface{}{"hello ada", "hello grace"}) {
+		t.Errorf("unexpected state: %v", state)
+	}
+}
+
+func TestSendEdge_Checkpointing(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := graph.NewFileCheckpointStoreWithDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	store.SetSerializer(graph.GobSerializer{})
+
+	g := graph.NewCheckpointableMessageGraphWithConfig(graph.CheckpointConfig{Store: store})
+	g.AddNode("plan", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	g.AddNode("square", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state.(int) * state.(int), nil
+	})
+	g.AddSendEdge("plan", func(_ context.Context, state interface{}) []graph.Send {
+		var sends []graph.Send
+		for i := 1; i <= state.(int); i++ {
+			sends = append(sends, graph.Send{Node: "square", State: i})
+		}
+		return sends
+	})
+	g.AddEdge("square", graph.END)
+	g.SetEntryPoint("plan")
+	g.SetStateMerger(func(_ context.Context, _ interface{}, updates []interface{}) (interface{}, error) {
+		sum := 0
+		for _, update := range updates {
+			sum += update.(int)
+		}
+		return sum, nil
+	})
+
+	runnable, err := g.CompileCheckpointable(graph.WithInterruptBefore("square"))
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	if _, err := runnable.InvokeWithConfig(ctx, 3, threadConfig("squares")); err == nil {
+		t.Fatal("expected the run to pause before square")
+	}
+
+	snapshot, err := runnable.GetState(ctx, "squares")
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+	if !reflect.DeepEqual(snapshot.Next, []string{"square"}) {
+		t.Errorf("expected square to be next, got %v", snapshot.Next)
+	}
+
+	checkpoint, err := runnable.LoadCheckpoint(ctx, snapshot.CheckpointID)
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+	inputs := []int{}
+	for _, send := range checkpoint.Sends {
+		inputs = append(inputs, send.State.(int))
+	}
+	sort.Ints(inputs)
+	if !reflect.DeepEqual(inputs, []int{1, 2, 3}) {
+		t.Errorf("expected the sends to be saved, got %v", checkpoint.Sends)
+	}
+
+	result, err := runnable.Resume(ctx, snapshot.CheckpointID)
+	if err != nil || result != 14 {
+		t.Errorf("expected the sum of squares, got %v (%v)", result, err)
+	}
+}
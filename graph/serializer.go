@@ -0,0 +1,238 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Serializer encodes checkpoint states for persistent checkpoint stores
+type Serializer interface {
+	// Marshal encodes a state
+	Marshal(state interface{}) ([]byte, error)
+
+	// Unmarshal decodes a state encoded by Marshal
+	Unmarshal(data []byte) (interface{}, error)
+}
+
+// JSONSerializer encodes states as plain JSON. Decoded states are made of the generic JSON
+// types: structs come back as map[string]interface{} and numbers as float64.
+type JSONSerializer struct{}
+
+// Marshal implements Serializer interface
+func (JSONSerializer) Marshal(state interface{}) ([]byte, error) {
+	return json.Marshal(state)
+}
+
+// Unmarshal implements Serializer interface
+func (JSONSerializer) Unmarshal(data []byte) (interface{}, error) {
+	var state interface{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// GobSerializer encodes states with encoding/gob, preserving their concrete types.
+// Types other than the predeclared ones must be registered with a TypeRegistry or gob.Register.
+type GobSerializer struct{}
+
+// Marshal implements Serializer interface
+func (GobSerializer) Marshal(state interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&state); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal implements Serializer interface
+func (GobSerializer) Unmarshal(data []byte) (interface{}, error) {
+	var state interface{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// TaggedJSONSerializer encodes states as JSON along with the name of their Go type, so that
+// states of types known to its TypeRegistry are decoded to their original type. States of
+// other types are decoded as by JSONSerializer.
+type TaggedJSONSerializer struct {
+	registry *TypeRegistry
+}
+
+// taggedState is the JSON document written by TaggedJSONSerializer
+type taggedState struct {
+	Type  string          `json:"type,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
+// NewTaggedJSONSerializer creates a tagged JSON serializer resolving types with the registry.
+// A nil registry is treated as an empty one.
+func NewTaggedJSONSerializer(registry *TypeRegistry) *TaggedJSONSerializer {
+	if registry == nil {
+		registry = NewTypeRegistry()
+	}
+	return &TaggedJSONSerializer{registry: registry}
+}
+
+// Marshal implements Serializer interface
+func (s *TaggedJSONSerializer) Marshal(state interface{}) ([]byte, error) {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	name, _ := s.registry.typeName(reflect.TypeOf(state))
+	return json.Marshal(taggedState{Type: name, Value: value})
+}
+
+// Unmarshal implements Serializer interface
+func (s *TaggedJSONSerializer) Unmarshal(data []byte) (interface{}, error) {
+	var tagged taggedState
+	if err := json.Unmarshal(data, &tagged); err != nil {
+		return nil, err
+	}
+
+	if tagged.Type == "" {
+		return JSONSerializer{}.Unmarshal(tagged.Value)
+	}
+
+	stateType, ok := s.registry.lookup(tagged.Type)
+	if !ok {
+		return nil, fmt.Errorf("state type %s is not registered", tagged.Type)
+	}
+
+	state := reflect.New(stateType)
+	if err := json.Unmarshal(tagged.Value, state.Interface()); err != nil {
+		return nil, err
+	}
+	return state.Elem().Interface(), nil
+}
+
+// TypeRegistry maps names to the Go types of states, so serializers can restore them.
+// Registering a named type also registers it with encoding/gob under the same name.
+type TypeRegistry struct {
+	mutex sync.RWMutex
+	types map[string]reflect.Type
+	names map[reflect.Type]string
+}
+
+// NewTypeRegistry creates a type registry that knows the common predeclared and JSON types
+func NewTypeRegistry() *TypeRegistry {
+	registry := &TypeRegistry{
+		types: make(map[string]reflect.Type),
+		names: make(map[reflect.Type]string),
+	}
+
+	registry.Register(
+		"", false, 0, int64(0), float64(0),
+		[]string(nil), []interface{}(nil), map[string]interface{}(nil),
+	)
+
+	return registry
+}
+
+// Register registers the types of the given values under their Go type names,
+// such as "github.com/acme/app.State" or "*github.com/acme/app.State"
+func (r *TypeRegistry) Register(values ...interface{}) {
+	for _, value := range values {
+		r.RegisterName(goTypeName(reflect.TypeOf(value)), value)
+	}
+}
+
+// RegisterName registers the type of value under a name of the caller's choosing,
+// which keeps existing checkpoints readable when the type is renamed or moved.
+// Like gob.RegisterName, it panics if the name or type is already registered differently.
+func (r *TypeRegistry) RegisterName(name string, value interface{}) {
+	valueType := reflect.TypeOf(value)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if existing, ok := r.types[name]; ok && existing != valueType {
+		panic(fmt.Sprintf("graph: registering duplicate types for %q: %s != %s", name, existing, valueType))
+	}
+	if existing, ok := r.names[valueType]; ok && existing != name {
+		panic(fmt.Sprintf("graph: registering duplicate names for %s: %q != %q", valueType, existing, name))
+	}
+
+	r.types[name] = valueType
+	r.names[valueType] = name
+
+	if valueType.Name() != "" && valueType.PkgPath() != "" {
+		gob.RegisterName(name, value)
+	}
+}
+
+// typeName returns the name a type is registered under
+func (r *TypeRegistry) typeName(valueType reflect.Type) (string, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	name, ok := r.names[valueType]
+	return name, ok
+}
+
+// lookup returns the type registered under a name
+func (r *TypeRegistry) lookup(name string) (reflect.Type, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	valueType, ok := r.types[name]
+	return valueType, ok
+}
+
+// goTypeName returns the package-qualified name of a type
+func goTypeName(valueType reflect.Type) string {
+	if valueType.Kind() == reflect.Pointer && valueType.Name() == "" {
+		return "*" + goTypeName(valueType.Elem())
+	}
+	if valueType.Name() != "" && valueType.PkgPath() != "" {
+		return valueType.PkgPath() + "." + valueType.Name()
+	}
+	return valueType.String()
+}
+
+// defaultSerializer returns the serializer to use when a store has none configured
+func defaultSerializer(serializer Serializer) Serializer {
+	if serializer == nil {
+		return JSONSerializer{}
+	}
+	return serializer
+}
+
+// encodeState encodes a state with the serializer. States encoded as valid JSON are returned
+// in document so stores can keep them readable; anything else is returned in binary.
+func encodeState(serializer Serializer, state interface{}) (document json.RawMessage, binary []byte, err error) {
+	data, err := defaultSerializer(serializer).Marshal(state)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal checkpoint state: %w", err)
+	}
+
+	if json.Valid(data) {
+		return data, nil, nil
+	}
+	return nil, data, nil
+}
+
+// decodeState decodes a state encoded by encodeState
+func decodeState(serializer Serializer, document json.RawMessage, binary []byte) (interface{}, error) {
+	data := binary
+	if data == nil {
+		data = document
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	state, err := defaultSerializer(serializer).Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checkpoint state: %w", err)
+	}
+	return state, nil
+}
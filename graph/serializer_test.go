@@ -0,0 +1,161 @@
+package graph_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+type ticketState struct {
+	Title    string
+	Priority int
+	Labels   []string
+}
+
+func newTicketRegistry() *graph.TypeRegistry {
+	registry := graph.NewTypeRegistry()
+	registry.Register(ticketState{}, &ticketState{})
+	return registry
+}
+
+func TestSerializers_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	registry := newTicketRegistry()
+	tests := []struct {
+		name       string
+		serializer graph.Serializer
+		state      interface{}
+		expected   interface{}
+	}{
+		{
+			name:       "json struct",
+			serializer: graph.JSONSerializer{},
+			state:      ticketState{Title: "bug", Priority: 2},
+			expected:   map[string]interface{}{"Title": "bug", "Priority": float64(2), "Labels": nil},
+		},
+		{
+			name:       "gob struct",
+			serializer: graph.GobSerializer{},
+			state:      ticketState{Title: "bug", Priority: 2, Labels: []string{"ui"}},
+			expected:   ticketState{Title: "bug", Priority: 2, Labels: []string{"ui"}},
+		},
+		{
+			name:       "gob int",
+			serializer: graph.GobSerializer{},
+			state:      42,
+			expected:   42,
+		},
+		{
+			name:       "tagged struct",
+			serializer: graph.NewTaggedJSONSerializer(registry),
+			state:      ticketState{Title: "bug", Priority: 2, Labels: []string{"ui"}},
+			expected:   ticketState{Title: "bug", Priority: 2, Labels: []string{"ui"}},
+		},
+		{
+			name:       "tagged pointer",
+			serializer: graph.NewTaggedJSONSerializer(registry),
+			state:      &ticketState{Title: "bug"},
+			expected:   &ticketState{Title: "bug"},
+		},
+		{
+			name:       "tagged int",
+			serializer: graph.NewTaggedJSONSerializer(registry),
+			state:      42,
+			expected:   42,
+		},
+		{
+			name:       "tagged unregistered",
+			serializer: graph.NewTaggedJSONSerializer(registry),
+			state:      struct{ Name string }{"anonymous"},
+			expected:   map[string]interface{}{"Name": "anonymous"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := tt.serializer.Marshal(tt.state)
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+
+			state, err := tt.serializer.Unmarshal(data)
+			if err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+			if !reflect.DeepEqual(state, tt.expected) {
+				t.Errorf("expected %#v, got %#v", tt.expected, state)
+			}
+		})
+	}
+}
+
+func TestTaggedJSONSerializer_UnknownType(t *testing.T) {
+	t.Parallel()
+
+	data, err := graph.NewTaggedJSONSerializer(newTicketRegistry()).Marshal(ticketState{Title: "bug"})
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	if _, err := graph.NewTaggedJSONSerializer(graph.NewTypeRegistry()).Unmarshal(data); err == nil {
+		t.Error("expected error for a type missing from the registry")
+	}
+}
+
+func TestTaggedJSONSerializer_NilRegistry(t *testing.T) {
+	t.Parallel()
+
+	serializer := graph.NewTaggedJSONSerializer(nil)
+	data, err := serializer.Marshal(ticketState{Title: "bug"})
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	state, err := serializer.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if decoded, _ := state.(map[string]interface{}); decoded["Title"] != "bug" {
+		t.Errorf("expected an untyped map, got %#v", state)
+	}
+}
+
+func TestFileCheckpointStore_Serializer(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	for name, serializer := range map[string]graph.Serializer{
+		"gob":    graph.GobSerializer{},
+		"tagged": graph.NewTaggedJSONSerializer(newTicketRegistry()),
+	} {
+		store, err := graph.NewFileCheckpointStoreWithDir(t.TempDir())
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		store.SetSerializer(serializer)
+
+		checkpoint := &graph.Checkpoint{
+			ID:        "cp",
+			ThreadID:  "tickets",
+			State:     ticketState{Title: "bug", Priority: 1},
+			Timestamp: time.Now(),
+		}
+		if err := store.Save(ctx, checkpoint); err != nil {
+			t.Fatalf("%s: failed to save checkpoint: %v", name, err)
+		}
+
+		loaded, err := store.Load(ctx, "cp")
+		if err != nil {
+			t.Fatalf("%s: failed to load checkpoint: %v", name, err)
+		}
+		if state, ok := loaded.State.(ticketState); !ok || state.Title != "bug" {
+			t.Errorf("%s: expected ticketState, got %#v", name, loaded.State)
+		}
+	}
+}
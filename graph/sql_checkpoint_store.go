@@ -0,0 +1,378 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// sqlMigrations return the statements that create and upgrade the schema of a
+// SQLCheckpointStore. Migration i brings the schema to version i+1; released migrations
+// must never change.
+func sqlMigrations(dialect SQLDialect) [][]string {
+	key, document, binary := dialect.KeyType(), dialect.JSONType(), dialect.BinaryType()
+
+	return [][]string{
+		{
+			`CREATE TABLE IF NOT EXISTS checkpoints (
+				id ` + key + ` NOT NULL PRIMARY KEY,
+				thread_id ` + key + ` NOT NULL,
+				parent_id ` + key + ` NOT NULL DEFAULT '',
+				node_name ` + key + ` NOT NULL DEFAULT '',
+				state ` + document + `,
+				metadata ` + document + `,
+				next_nodes ` + document + `,
+				version INTEGER NOT NULL DEFAULT 0,
+				created_at BIGINT NOT NULL
+			)`,
+			`CREATE INDEX idx_checkpoints_thread_id ON checkpoints (thread_id, version)`,
+			`CREATE INDEX idx_checkpoints_node_name ON checkpoints (node_name)`,
+			`CREATE INDEX idx_checkpoints_created_at ON checkpoints (created_at)`,
+		},
+		{
+			// States whose serializer output is not JSON
+			`ALTER TABLE checkpoints ADD COLUMN encoded_state ` + binary,
+		},
+		{
+			// Outputs of the tasks that completed in a failed step
+			`ALTER TABLE checkpoints ADD COLUMN pending_writes ` + document,
+		},
+		{
+			// Nodes sent their own input that run when the checkpoint is resumed
+			`ALTER TABLE checkpoints ADD COLUMN sends ` + document,
+		},
+	}
+}
+
+// sqlCheckpointColumns lists the columns of a checkpoint row, in the order they are written and read
+var sqlCheckpointColumns = []string{
+	"id", "thread_id", "parent_id", "node_name", "state", "encoded_state", "metadata", "next_nodes", "sends", "pending_writes",
+	"version", "created_at",
+}
+
+// SQLCheckpointStore provides checkpoint storage in a SQL database through database/sql.
+// Differences between database engines are handled by its SQLDialect. The schema is
+// created, or migrated to the current version, when the store is created.
+type SQLCheckpointStore struct {
+	db         *sql.DB
+	dialect    SQLDialect
+	serializer Serializer
+
+	// ownsDB is set when the store opened the database and must close it
+	ownsDB bool
+}
+
+// NewSQLCheckpointStore creates a checkpoint store on an open database using the given dialect
+func NewSQLCheckpointStore(ctx context.Context, db *sql.DB, dialect SQLDialect) (*SQLCheckpointStore, error) {
+	store := &SQLCheckpointStore{db: db, dialect: dialect}
+	if err := store.migrate(ctx); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// SetSerializer sets the serializer used for checkpoint states. JSONSerializer is used by default.
+func (s *SQLCheckpointStore) SetSerializer(serializer Serializer) {
+	s.serializer = serializer
+}
+
+// Close closes the database if it was opened by the store
+func (s *SQLCheckpointStore) Close() error {
+	if !s.ownsDB {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// SchemaVersion returns the version of the store's database schema
+func (s *SQLCheckpointStore) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	err := s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM checkpoint_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// migrate applies the migrations the database has not seen yet, each in its own transaction.
+// Engines that commit schema changes implicitly, such as MySQL, cannot roll a failed migration back.
+func (s *SQLCheckpointStore) migrate(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS checkpoint_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	current, err := s.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	migrations := sqlMigrations(s.dialect)
+	for version := current + 1; version <= len(migrations); version++ {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", version, err)
+		}
+
+		for _, statement := range migrations[version-1] {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				_ = tx.Rollback()
+				return fmt.Errorf("failed to apply migration %d: %w", version, err)
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, s.rebind(`INSERT INTO checkpoint_migrations (version, applied_at) VALUES (?, ?)`),
+			version, time.Now().UnixNano())
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", version, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", version, err)
+		}
+	}
+
+	return nil
+}
+
+// Save implements CheckpointStore interface, replacing any checkpoint with the same ID
+func (s *SQLCheckpointStore) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	document, encodedState, err := encodeState(s.serializer, checkpoint.State)
+	if err != nil {
+		return err
+	}
+	state := sql.NullString{String: string(document), Valid: document != nil}
+
+	metadata, err := json.Marshal(checkpoint.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint metadata: %w", err)
+	}
+	next, err := json.Marshal(checkpoint.Next)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint next nodes: %w", err)
+	}
+
+	var sends sql.NullString
+	if len(checkpoint.Sends) > 0 {
+		records, err := encodeSends(s.serializer, checkpoint.Sends)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(records)
+		if err != nil {
+			return fmt.Errorf("failed to marshal checkpoint sends: %w", err)
+		}
+		sends = sql.NullString{String: string(data), Valid: true}
+	}
+
+	var pendingWrites sql.NullString
+	if len(checkpoint.PendingWrites) > 0 {
+		records, err := encodePendingWrites(s.serializer, checkpoint.PendingWrites)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(records)
+		if err != nil {
+			return fmt.Errorf("failed to marshal checkpoint pending writes: %w", err)
+		}
+		pendingWrites = sql.NullString{String: string(data), Valid: true}
+	}
+
+	query := fmt.Sprintf("INSERT INTO checkpoints (%s) VALUES (%s) %s",
+		strings.Join(sqlCheckpointColumns, ", "),
+		strings.TrimSuffix(strings.Repeat("?, ", len(sqlCheckpointColumns)), ", "),
+		s.dialect.UpsertClause("id", sqlCheckpointColumns[1:]))
+
+	_, err = s.db.ExecContext(ctx, s.rebind(query),
+		checkpoint.ID, checkpointThreadID(checkpoint), checkpoint.ParentID, checkpoint.NodeName,
+		state, encodedState, string(metadata), string(next), sends, pendingWrites, checkpoint.Version, checkpoint.Timestamp.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// Load implements CheckpointStore interface
+func (s *SQLCheckpointStore) Load(ctx context.Context, checkpointID string) (*Checkpoint, error) {
+	row := s.db.QueryRowContext(ctx, s.rebind(s.selectCheckpoints("id = ?", "")), checkpointID)
+
+	checkpoint, err := s.scanCheckpoint(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("checkpoint not found: %s", checkpointID)
+	}
+	return checkpoint, err
+}
+
+// List implements CheckpointStore interface, returning the checkpoints of a thread oldest first
+func (s *SQLCheckpointStore) List(ctx context.Context, executionID string) ([]*Checkpoint, error) {
+	return s.query(ctx, s.selectCheckpoints("thread_id = ?", "version, created_at"), executionID)
+}
+
+// History returns the latest checkpoints of a thread, newest first.
+// A limit of zero or less returns every checkpoint.
+func (s *SQLCheckpointStore) History(ctx context.Context, threadID string, limit int) ([]*Checkpoint, error) {
+	query := s.selectCheckpoints("thread_id = ?", "version DESC, created_at DESC")
+	if limit > 0 {
+		return s.query(ctx, query+" LIMIT ?", threadID, limit)
+	}
+	return s.query(ctx, query, threadID)
+}
+
+// ListByNode returns the checkpoints of a thread saved after the given node, oldest first
+func (s *SQLCheckpointStore) ListByNode(ctx context.Context, threadID, nodeName string) ([]*Checkpoint, error) {
+	return s.query(ctx, s.selectCheckpoints("thread_id = ? AND node_name = ?", "version, created_at"), threadID, nodeName)
+}
+
+// Threads returns the IDs of all threads with checkpoints, most recently updated first
+func (s *SQLCheckpointStore) Threads(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT thread_id FROM checkpoints
+		GROUP BY thread_id ORDER BY MAX(created_at) DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list threads: %w", err)
+	}
+	defer rows.Close()
+
+	var threads []string
+	for rows.Next() {
+		var threadID string
+		if err := rows.Scan(&threadID); err != nil {
+			return nil, fmt.Errorf("failed to read thread: %w", err)
+		}
+		threads = append(threads, threadID)
+	}
+
+	return threads, rows.Err()
+}
+
+// Delete implements CheckpointStore interface
+func (s *SQLCheckpointStore) Delete(ctx context.Context, checkpointID string) error {
+	if _, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM checkpoints WHERE id = ?`), checkpointID); err != nil {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Clear implements CheckpointStore interface
+func (s *SQLCheckpointStore) Clear(ctx context.Context, executionID string) error {
+	if _, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM checkpoints WHERE thread_id = ?`), executionID); err != nil {
+		return fmt.Errorf("failed to clear checkpoints: %w", err)
+	}
+	return nil
+}
+
+// selectCheckpoints returns a query for the checkpoints matching a condition
+func (s *SQLCheckpointStore) selectCheckpoints(where, orderBy string) string {
+	query := fmt.Sprintf("SELECT %s FROM checkpoints WHERE %s", strings.Join(sqlCheckpointColumns, ", "), where)
+	if orderBy != "" {
+		query += " ORDER BY " + orderBy
+	}
+	return query
+}
+
+// rebind replaces the ? placeholders of a query with those of the store's dialect
+func (s *SQLCheckpointStore) rebind(query string) string {
+	var rebound strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			rebound.WriteString(s.dialect.Placeholder(n))
+			continue
+		}
+		rebound.WriteRune(r)
+	}
+	return rebound.String()
+}
+
+// query runs a query returning checkpoint rows
+func (s *SQLCheckpointStore) query(ctx context.Context, query string, args ...interface{}) ([]*Checkpoint, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var checkpoints []*Checkpoint
+	for rows.Next() {
+		checkpoint, err := s.scanCheckpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query checkpoints: %w", err)
+	}
+
+	return checkpoints, nil
+}
+
+// scanCheckpoint decodes a checkpoint row
+func (s *SQLCheckpointStore) scanCheckpoint(row interface {
+	Scan(dest ...interface{}) error
+}) (*Checkpoint, error) {
+	var (
+		checkpoint                                  Checkpoint
+		state, metadata, next, sends, pendingWrites sql.NullString
+		encodedState                                []byte
+		createdAt                                   int64
+	)
+
+	err := row.Scan(&checkpoint.ID, &checkpoint.ThreadID, &checkpoint.ParentID, &checkpoint.NodeName,
+		&state, &encodedState, &metadata, &next, &sends, &pendingWrites, &checkpoint.Version, &createdAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var document json.RawMessage
+	if state.Valid {
+		document = json.RawMessage(state.String)
+	}
+	if checkpoint.State, err = decodeState(s.serializer, document, encodedState); err != nil {
+		return nil, err
+	}
+	if metadata.Valid {
+		if err := json.Unmarshal([]byte(metadata.String), &checkpoint.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal checkpoint metadata: %w", err)
+		}
+	}
+	if next.Valid {
+		if err := json.Unmarshal([]byte(next.String), &checkpoint.Next); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal checkpoint next nodes: %w", err)
+		}
+	}
+	if sends.Valid {
+		var records []sendRecord
+		if err := json.Unmarshal([]byte(sends.String), &records); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal checkpoint sends: %w", err)
+		}
+		if checkpoint.Sends, err = decodeSends(s.serializer, records); err != nil {
+			return nil, err
+		}
+	}
+	if pendingWrites.Valid {
+		var records []pendingWriteRecord
+		if err := json.Unmarshal([]byte(pendingWrites.String), &records); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal checkpoint pending writes: %w", err)
+		}
+		if checkpoint.PendingWrites, err = decodePendingWrites(s.serializer, records); err != nil {
+			return nil, err
+		}
+	}
+	checkpoint.Timestamp = time.Unix(0, createdAt)
+
+	return &checkpoint, nil
+}
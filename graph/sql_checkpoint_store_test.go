@@ -0,0 +1,337 @@
+package graph_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+// openSQLiteStore opens a store in a temporary file
+func openSQLiteStore(t *testing.T) *graph.SQLCheckpointStore {
+	t.Helper()
+
+	registered := false
+	for _, driver := range sql.Drivers() {
+		registered = registered || driver == graph.SQLiteDriverName
+	}
+	if !registered {
+		t.Fatalf("no %q database/sql driver registered", graph.SQLiteDriverName)
+	}
+
+	store, err := graph.OpenSQLiteCheckpointStore(context.Background(), filepath.Join(t.TempDir(), "checkpoints.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	return store
+}
+
+// checkpointIDs returns the IDs of checkpoints in order
+func checkpointIDs(checkpoints []*graph.Checkpoint) []string {
+	ids := make([]string, len(checkpoints))
+	for i, checkpoint := range checkpoints {
+		ids[i] = checkpoint.ID
+	}
+	return ids
+}
+
+func TestSQLCheckpointStore_Operations(t *testing.T) {
+	t.Parallel()
+
+	store := openSQLiteStore(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	checkpoints := []*graph.Checkpoint{
+		{ID: "cp_1", ThreadID: "a", NodeName: "first", State: "one", Version: 1, Timestamp: now},
+		{ID: "cp_2", ThreadID: "a", NodeName: "second", State: "two", Version: 2, Timestamp: now.Add(time.Second),
+			ParentID: "cp_1", Next: []string{"third"}, PendingWrites: []graph.PendingWrite{{TaskID: "third/a", Value: "done"}},
+			Sends: []graph.Send{{Node: "fourth", State: "item"}}},
+		{ID: "cp_3", NodeName: "first", State: "three", Version: 1, Timestamp: now.Add(2 * time.Second),
+			Metadata: map[string]interface{}{"execution_id": "b"}},
+	}
+	for _, checkpoint := range checkpoints {
+		if err := store.Save(ctx, checkpoint); err != nil {
+			t.Fatalf("failed to save checkpoint: %v", err)
+		}
+	}
+
+	loaded, err := store.Load(ctx, "cp_2")
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+	if loaded.State != "two" || loaded.ParentID != "cp_1" || loaded.ThreadID != "a" ||
+		len(loaded.Next) != 1 || loaded.Next[0] != "third" || !loaded.Timestamp.Equal(now.Add(time.Second)) ||
+		len(loaded.PendingWrites) != 1 || loaded.PendingWrites[0].TaskID != "third/a" || loaded.PendingWrites[0].Value != "done" ||
+		len(loaded.Sends) != 1 || loaded.Sends[0].Node != "fourth" || loaded.Sends[0].State != "item" {
+		t.Errorf("unexpected checkpoint: %+v", loaded)
+	}
+
+	if _, err := store.Load(ctx, "missing"); err == nil || !strings.Contains(err.Error(), "checkpoint not found") {
+		t.Errorf("expected 'checkpoint not found' error, got %v", err)
+	}
+
+	listed, err := store.List(ctx, "a")
+	if err != nil {
+		t.Fatalf("failed to list checkpoints: %v", err)
+	}
+	if ids := checkpointIDs(listed); !reflect.DeepEqual(ids, []string{"cp_1", "cp_2"}) {
+		t.Errorf("expected thread checkpoints oldest first, got %v", ids)
+	}
+
+	history, err := store.History(ctx, "a", 1)
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	if ids := checkpointIDs(history); !reflect.DeepEqual(ids, []string{"cp_2"}) {
+		t.Errorf("expected only the latest checkpoint, got %v", ids)
+	}
+
+	history, err = store.History(ctx, "a", 0)
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	if ids := checkpointIDs(history); !reflect.DeepEqual(ids, []string{"cp_2", "cp_1"}) {
+		t.Errorf("expected every checkpoint newest first, got %v", ids)
+	}
+
+	byNode, err := store.ListByNode(ctx, "b", "first")
+	if err != nil {
+		t.Fatalf("failed to list checkpoints by node: %v", err)
+	}
+	if ids := checkpointIDs(byNode); !reflect.DeepEqual(ids, []string{"cp_3"}) {
+		t.Errorf("expected the checkpoint of node first in thread b, got %v", ids)
+	}
+
+	threads, err := store.Threads(ctx)
+	if err != nil {
+		t.Fatalf("failed to list threads: %v", err)
+	}
+	if !reflect.DeepEqual(threads, []string{"b", "a"}) {
+		t.Errorf("expected threads b and a, got %v", threads)
+	}
+
+	// Saving again replaces the checkpoint
+	checkpoints[0].State = "updated"
+	if err := store.Save(ctx, checkpoints[0]); err != nil {
+		t.Fatalf("failed to save checkpoint: %v", err)
+	}
+	if loaded, err := store.Load(ctx, "cp_1"); err != nil || loaded.State != "updated" {
+		t.Errorf("expected updated checkpoint, got %v (%v)", loaded, err)
+	}
+
+	if err := store.Delete(ctx, "cp_1"); err != nil {
+		t.Fatalf("failed to delete checkpoint: %v", err)
+	}
+	if err := store.Clear(ctx, "b"); err != nil {
+		t.Fatalf("failed to clear checkpoints: %v", err)
+	}
+	if threads, _ := store.Threads(ctx); !reflect.DeepEqual(threads, []string{"a"}) {
+		t.Errorf("expected only thread a to be left, got %v", threads)
+	}
+	if listed, _ := store.List(ctx, "a"); !reflect.DeepEqual(checkpointIDs(listed), []string{"cp_2"}) {
+		t.Errorf("expected only cp_2 to be left, got %v", checkpointIDs(listed))
+	}
+	if listed, _ := store.List(ctx, "b"); len(listed) != 0 {
+		t.Errorf("expected thread b to be cleared, got %v", checkpointIDs(listed))
+	}
+}
+
+func TestSQLCheckpointStore_Pruning(t *testing.T) {
+	t.Parallel()
+
+	store := openSQLiteStore(t)
+	runnable, err := newCounterGraph(6, graph.CheckpointConfig{
+		Store:          store,
+		AutoSave:       true,
+		MaxCheckpoints: 2,
+		KeepFirst:      true,
+	}).CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	if result, err := runnable.InvokeWithConfig(ctx, 0, threadConfig("pruned")); err != nil || result != 6 {
+		t.Fatalf("expected 6, got %v (%v)", result, err)
+	}
+
+	// Only the rows kept by pruning are left in the table
+	listed, err := store.List(ctx, "pruned")
+	if err != nil {
+		t.Fatalf("failed to list checkpoints: %v", err)
+	}
+	var listedVersions []int
+	for _, checkpoint := range listed {
+		listedVersions = append(listedVersions, checkpoint.Version)
+	}
+	if expected := []int{1, 5, 6}; !reflect.DeepEqual(listedVersions, expected) {
+		t.Errorf("expected versions %v, got %v", expected, listedVersions)
+	}
+
+	history, err := store.History(ctx, "pruned", 2)
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	var historyNodes []string
+	for _, checkpoint := range history {
+		historyNodes = append(historyNodes, checkpoint.NodeName)
+	}
+	if expected := []string{"step_5", "step_4"}; !reflect.DeepEqual(historyNodes, expected) {
+		t.Errorf("expected nodes %v, got %v", expected, historyNodes)
+	}
+}
+
+func TestSQLCheckpointStore_Reopen(t *testing.T) {
+	t.Parallel()
+
+	store := openSQLiteStore(t)
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "reopen.db")
+	first, err := graph.OpenSQLiteCheckpointStore(ctx, path)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	if err := first.Save(ctx, &graph.Checkpoint{ID: "cp", ThreadID: "t", State: "kept", Timestamp: time.Now()}); err != nil {
+		t.Fatalf("failed to save checkpoint: %v", err)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatalf("failed to close store: %v", err)
+	}
+
+	// Opening an existing database leaves the schema and data as they are
+	second, err := graph.OpenSQLiteCheckpointStore(ctx, path)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	defer second.Close()
+
+	if loaded, err := second.Load(ctx, "cp"); err != nil || loaded.State != "kept" {
+		t.Errorf("expected checkpoint to survive reopening, got %v (%v)", loaded, err)
+	}
+
+	version, err := second.SchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("failed to read schema version: %v", err)
+	}
+	if expected, _ := store.SchemaVersion(ctx); version != expected || version == 0 {
+		t.Errorf("expected schema version %d, got %d", expected, version)
+	}
+}
+
+func TestSQLCheckpointStore_ThreadHistory(t *testing.T) {
+	t.Parallel()
+
+	g := newReviewGraph()
+	g.SetCheckpointConfig(graph.CheckpointConfig{Store: openSQLiteStore(t), AutoSave: true})
+
+	runnable, err := g.CompileCheckpointable(graph.WithInterruptBefore("publish"))
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := runnable.InvokeWithConfig(ctx, "post", threadConfig("sqlite")); err == nil {
+		t.Fatal("expected the run to be interrupted")
+	}
+
+	result, err := runnable.InvokeWithConfig(ctx, nil, threadConfig("sqlite"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "post draft published" {
+		t.Errorf("unexpected result: %v", result)
+	}
+
+	history, err := runnable.GetStateHistory(ctx, "sqlite")
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	if len(history) != 3 || history[0].NodeName != "publish" {
+		t.Errorf("expected 3 checkpoints ending with publish, got %d", len(history))
+	}
+}
+
+func TestSQLDialects(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		dialect     graph.SQLDialect
+		placeholder string
+		upsert      string
+		jsonType    string
+	}{
+		{
+			name:        "sqlite",
+			dialect:     graph.SQLiteDialect{},
+			placeholder: "?",
+			upsert:      "ON CONFLICT (id) DO UPDATE SET state = excluded.state, version = excluded.version",
+			jsonType:    "TEXT",
+		},
+		{
+			name:        "postgres",
+			dialect:     graph.PostgresDialect{},
+			placeholder: "$3",
+			upsert:      "ON CONFLICT (id) DO UPDATE SET state = excluded.state, version = excluded.version",
+			jsonType:    "JSONB",
+		},
+		{
+			name:        "mysql",
+			dialect:     graph.MySQLDialect{},
+			placeholder: "?",
+			upsert:      "ON DUPLICATE KEY UPDATE state = VALUES(state), version = VALUES(version)",
+			jsonType:    "JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.dialect.Placeholder(3); got != tt.placeholder {
+				t.Errorf("expected placeholder %q, got %q", tt.placeholder, got)
+			}
+			if got := tt.dialect.UpsertClause("id", []string{"state", "version"}); got != tt.upsert {
+				t.Errorf("expected upsert %q, got %q", tt.upsert, got)
+			}
+			if got := tt.dialect.JSONType(); got != tt.jsonType {
+				t.Errorf("expected JSON type %q, got %q", tt.jsonType, got)
+			}
+		})
+	}
+}
+
+func TestSQLCheckpointStore_Serializer(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	for name, serializer := range map[string]graph.Serializer{
+		"gob":    graph.GobSerializer{},
+		"tagged": graph.NewTaggedJSONSerializer(newTicketRegistry()),
+	} {
+		store := openSQLiteStore(t)
+		store.SetSerializer(serializer)
+
+		checkpoint := &graph.Checkpoint{ID: "cp", ThreadID: "tickets", State: ticketState{Title: "bug"}, Timestamp: time.Now()}
+		if err := store.Save(ctx, checkpoint); err != nil {
+			t.Fatalf("%s: failed to save checkpoint: %v", name, err)
+		}
+
+		loaded, err := store.Load(ctx, "cp")
+		if err != nil {
+			t.Fatalf("%s: failed to load checkpoint: %v", name, err)
+		}
+		if state, ok := loaded.State.(ticketState); !ok || state.Title != "bug" {
+			t.Errorf("%s: expected ticketState, got %#v", name, loaded.State)
+		}
+	}
+}
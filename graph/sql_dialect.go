@@ -0,0 +1,137 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// SQLDialect describes the SQL differences between database engines that matter to SQLCheckpointStore
+type SQLDialect interface {
+	// Placeholder returns the bind parameter for the n-th argument of a statement, starting at 1
+	Placeholder(n int) string
+
+	// UpsertClause returns the clause appended to an INSERT statement that updates the given
+	// columns of the existing row when a row with the same key is already present
+	UpsertClause(key string, columns []string) string
+
+	// JSONType returns the column type used for JSON documents
+	JSONType() string
+
+	// KeyType returns the column type used for indexed identifiers
+	KeyType() string
+
+	// BinaryType returns the column type used for binary data
+	BinaryType() string
+}
+
+// SQLiteDialect is the SQLDialect for SQLite
+type SQLiteDialect struct{}
+
+// Placeholder implements SQLDialect interface
+func (SQLiteDialect) Placeholder(int) string { return "?" }
+
+// UpsertClause implements SQLDialect interface
+func (SQLiteDialect) UpsertClause(key string, columns []string) string {
+	return onConflictClause(key, columns)
+}
+
+// JSONType implements SQLDialect interface
+func (SQLiteDialect) JSONType() string { return "TEXT" }
+
+// KeyType implements SQLDialect interface
+func (SQLiteDialect) KeyType() string { return "TEXT" }
+
+// BinaryType implements SQLDialect interface
+func (SQLiteDialect) BinaryType() string { return "BLOB" }
+
+// PostgresDialect is the SQLDialect for PostgreSQL
+type PostgresDialect struct{}
+
+// Placeholder implements SQLDialect interface
+func (PostgresDialect) Placeholder(n int) string { return fmt.Sprintf("$%d", n) }
+
+// UpsertClause implements SQLDialect interface
+func (PostgresDialect) UpsertClause(key string, columns []string) string {
+	return onConflictClause(key, columns)
+}
+
+// JSONType implements SQLDialect interface
+func (PostgresDialect) JSONType() string { return "JSONB" }
+
+// KeyType implements SQLDialect interface
+func (PostgresDialect) KeyType() string { return "TEXT" }
+
+// BinaryType implements SQLDialect interface
+func (PostgresDialect) BinaryType() string { return "BYTEA" }
+
+// MySQLDialect is the SQLDialect for MySQL and MariaDB
+type MySQLDialect struct{}
+
+// Placeholder implements SQLDialect interface
+func (MySQLDialect) Placeholder(int) string { return "?" }
+
+// UpsertClause implements SQLDialect interface
+func (MySQLDialect) UpsertClause(_ string, columns []string) string {
+	assignments := make([]string, len(columns))
+	for i, column := range columns {
+		assignments[i] = fmt.Sprintf("%s = VALUES(%s)", column, column)
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(assignments, ", ")
+}
+
+// JSONType implements SQLDialect interface
+func (MySQLDialect) JSONType() string { return "JSON" }
+
+// KeyType implements SQLDialect interface.
+// MySQL can only index TEXT columns by prefix, so identifiers are bounded.
+func (MySQLDialect) KeyType() string { return "VARCHAR(255)" }
+
+// BinaryType implements SQLDialect interface
+func (MySQLDialect) BinaryType() string { return "LONGBLOB" }
+
+// onConflictClause returns the standard upsert clause shared by SQLite and PostgreSQL
+func onConflictClause(key string, columns []string) string {
+	assignments := make([]string, len(columns))
+	for i, column := range columns {
+		assignments[i] = fmt.Sprintf("%s = excluded.%s", column, column)
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", key, strings.Join(assignments, ", "))
+}
+
+// SQLiteDriverName is the database/sql driver name used by OpenSQLiteCheckpointStore.
+// It is registered by pure-Go drivers such as modernc.org/sqlite, which must be imported
+// by the application:
+//
+//	import _ "modernc.org/sqlite"
+//
+// Other drivers can be registered under this name with sql.Register.
+const SQLiteDriverName = "sqlite"
+
+// NewSQLiteCheckpointStore creates a checkpoint store on an open SQLite database
+func NewSQLiteCheckpointStore(ctx context.Context, db *sql.DB) (*SQLCheckpointStore, error) {
+	return NewSQLCheckpointStore(ctx, db, SQLiteDialect{})
+}
+
+// OpenSQLiteCheckpointStore opens, or creates, the SQLite database file at path and
+// returns a checkpoint store on it. The driver registered as SQLiteDriverName is used,
+// and the database is closed with the store.
+func OpenSQLiteCheckpointStore(ctx context.Context, path string) (*SQLCheckpointStore, error) {
+	db, err := sql.Open(SQLiteDriverName, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint database: %w", err)
+	}
+
+	// SQLite allows a single writer; one connection avoids "database is locked" errors
+	db.SetMaxOpenConns(1)
+
+	store, err := NewSQLiteCheckpointStore(ctx, db)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	store.ownsDB = true
+
+	return store, nil
+}
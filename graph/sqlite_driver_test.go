@@ -0,0 +1,5 @@
+package graph_test
+
+// The SQL checkpoint store tests run against the pure-Go SQLite driver, which registers
+// itself as graph.SQLiteDriverName
+import _ "modernc.org/sqlite"
@@ -2,26 +2,45 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 )
 
-// StateGraph represents a state-based graph similar to Python's LangGraph StateGraph
-type StateGraph struct {
-	// nodes is a map of node names to their corresponding Node objects
-	nodes map[string]Node
+// TypedStateGraph represents a state-based graph similar to Python's LangGraph StateGraph,
+// where every node reads and writes a state of the static type S
+type TypedStateGraph[S any] struct {
+	// nodes is a map of node names to their corresponding TypedNode objects
+	nodes map[string]TypedNode[S]
 
 	// edges is a slice of Edge objects representing the connections between nodes
 	edges []Edge
 
 	// conditionalEdges contains a map between "From" node, while "To" node is derived based on the condition
-	conditionalEdges map[string]func(ctx context.Context, state interface{}) string
+	conditionalEdges map[string]func(ctx context.Context, state S) string
+
+	// sendEdges maps a node to the function that sends the next nodes their own input
+	sendEdges map[string]func(ctx context.Context, state S) []Send
+
+	// pathMaps lists the targets of conditional edges declared with their possible targets,
+	// keyed by the value the condition returns for each
+	pathMaps map[string]map[string]string
 
 	// entryPoint is the name of the entry point node in the graph
 	entryPoint string
 
 	// retryPolicy defines retry behavior for failed nodes
 	retryPolicy *RetryPolicy
+
+	// schema folds the partial updates returned by nodes into the state
+	schema StateSchema[S]
+
+	// merge combines the updates of nodes that run in the same superstep
+	merge StateMergeFunc[S]
+
+	// middleware wraps every node of the graph
+	middleware []TypedMiddleware[S]
 }
 
 // RetryPolicy defines how to handle node failures
@@ -40,24 +59,30 @@ const (
 	LinearBackoff
 )
 
-// NewStateGraph creates a new instance of StateGraph
-func NewStateGraph() *StateGraph {
-	return &StateGraph{
-		nodes:            make(map[string]Node),
-		conditionalEdges: make(map[string]func(ctx context.Context, state interface{}) string),
+// NewTypedStateGraph creates a new instance of TypedStateGraph
+func NewTypedStateGraph[S any]() *TypedStateGraph[S] {
+	return &TypedStateGraph[S]{
+		nodes:            make(map[string]TypedNode[S]),
+		conditionalEdges: make(map[string]func(ctx context.Context, state S) string),
+		sendEdges:        make(map[string]func(ctx context.Context, state S) []Send),
+		pathMaps:         make(map[string]map[string]string),
 	}
 }
 
-// AddNode adds a new node to the state graph with the given name and function
-func (g *StateGraph) AddNode(name string, fn func(ctx context.Context, state interface{}) (interface{}, error)) {
-	g.nodes[name] = Node{
-		Name:     name,
-		Function: fn,
-	}
+// AddNode adds a new node to the state graph with the given name and function.
+// Options such as WithMiddleware configure the node.
+func (g *TypedStateGraph[S]) AddNode(name string, fn func(ctx context.Context, state S) (S, error), opts ...TypedNodeOption[S]) {
+	g.nodes[name] = newNode(name, fn, opts)
+}
+
+// Use adds middlewares applied to every node of the graph when it is compiled. They wrap
+// the middlewares of each node, the first being the outermost.
+func (g *TypedStateGraph[S]) Use(middleware ...TypedMiddleware[S]) {
+	g.middleware = append(g.middleware, middleware...)
 }
 
 // AddEdge adds a new edge to the state graph between the "from" and "to" nodes
-func (g *StateGraph) AddEdge(from, to string) {
+func (g *TypedStateGraph[S]) AddEdge(from, to string) {
 	g.edges = append(g.edges, Edge{
 		From: from,
 		To:   to,
@@ -65,136 +90,138 @@ func (g *StateGraph) AddEdge(from, to string) {
 }
 
 // AddConditionalEdge adds a conditional edge where the target node is determined at runtime
-func (g *StateGraph) AddConditionalEdge(from string, condition func(ctx context.Context, state interface{}) string) {
+func (g *TypedStateGraph[S]) AddConditionalEdge(from string, condition func(ctx context.Context, state S) string) {
 	g.conditionalEdges[from] = condition
+	delete(g.pathMaps, from)
+}
+
+// AddConditionalEdgeWithPathMap adds a conditional edge whose condition returns a key of the
+// path map, which names the node to route to. Compile checks that every node of the path map exists.
+func (g *TypedStateGraph[S]) AddConditionalEdgeWithPathMap(from string, condition func(ctx context.Context, state S) string, pathMap map[string]string) {
+	g.conditionalEdges[from] = condition
+	g.pathMaps[from] = copyPathMap(pathMap)
+}
+
+// AddConditionalEdgeWithTargets adds a conditional edge whose condition returns one of the
+// given nodes. Compile checks that every target exists.
+func (g *TypedStateGraph[S]) AddConditionalEdgeWithTargets(from string, condition func(ctx context.Context, state S) string, targets ...string) {
+	g.AddConditionalEdgeWithPathMap(from, condition, targetPathMap(targets))
+}
+
+// AddSendEdge adds a conditional edge that fans out over data found at runtime: the nodes
+// it sends run once per Send with the state they were sent, and their partial updates are
+// folded into the state through the schema. Config.MaxConcurrency bounds how many run at once.
+func (g *TypedStateGraph[S]) AddSendEdge(from string, send func(ctx context.Context, state S) []Send) {
+	g.sendEdges[from] = send
 }
 
 // SetEntryPoint sets the entry point node name for the state graph
-func (g *StateGraph) SetEntryPoint(name string) {
+func (g *TypedStateGraph[S]) SetEntryPoint(name string) {
 	g.entryPoint = name
 }
 
 // SetRetryPolicy sets the retry policy for the graph
-func (g *StateGraph) SetRetryPolicy(policy *RetryPolicy) {
+func (g *TypedStateGraph[S]) SetRetryPolicy(policy *RetryPolicy) {
 	g.retryPolicy = policy
 }
 
-// StateRunnable represents a compiled state graph that can be invoked
-type StateRunnable struct {
-	graph *StateGraph
+// SetStateMerger sets the function used to combine the states returned by nodes
+// that run concurrently in the same step
+func (g *TypedStateGraph[S]) SetStateMerger(merge StateMergeFunc[S]) {
+	g.merge = merge
 }
 
-// Compile compiles the state graph and returns a StateRunnable instance
-func (g *StateGraph) Compile() (*StateRunnable, error) {
-	if g.entryPoint == "" {
-		return nil, ErrEntryPointNotSet
-	}
-
-	return &StateRunnable{
-		graph: g,
-	}, nil
+// SetSchema sets the state schema. Nodes then return partial updates, which are folded
+// into the state through the schema; it takes precedence over the state merger.
+func (g *TypedStateGraph[S]) SetSchema(schema StateSchema[S]) {
+	g.schema = schema
 }
 
-// Invoke executes the compiled state graph with the given input state
-func (r *StateRunnable) Invoke(ctx context.Context, initialState interface{}) (interface{}, error) {
-	state := initialState
-	currentNode := r.graph.entryPoint
-
-	for {
-		if currentNode == END {
-			break
-		}
-
-		node, ok := r.graph.nodes[currentNode]
-		if !ok {
-			return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, currentNode)
-		}
-
-		// Execute node with retry logic
-		var err error
-		state, err = r.executeNodeWithRetry(ctx, node, state)
-		if err != nil {
-			return nil, fmt.Errorf("error in node %s: %w", currentNode, err)
-		}
+// TypedStateRunnable represents a compiled TypedStateGraph that can be invoked
+type TypedStateRunnable[S any] struct {
+	graph   *TypedStateGraph[S]
+	nodes   map[string]TypedNode[S]
+	tracer  *Tracer
+	options compileOptions
+}
 
-		// First check for conditional edges
-		nextNodeFn, hasConditional := r.graph.conditionalEdges[currentNode]
-		if hasConditional {
-			currentNode = nextNodeFn(ctx, state)
-			if currentNode == "" {
-				return nil, fmt.Errorf("conditional edge returned empty next node from %s", currentNode)
-			}
-			continue
-		}
+// Validate checks the structure of the graph. It returns a *ValidationError listing every
+// problem found, including the warnings that don't prevent the graph from compiling: nodes
+// without outgoing edges, unreachable nodes and cycles that never reach END, which are
+// fine when the nodes route with a Command.
+func (g *TypedStateGraph[S]) Validate() error {
+	if problems := g.topology().validate(); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
 
-		// Then check regular edges
-		foundNext := false
-		for _, edge := range r.graph.edges {
-			if edge.From == currentNode {
-				currentNode = edge.To
-				foundNext = true
-				break
-			}
-		}
+func (g *TypedStateGraph[S]) topology() topology {
+	return newTopology(g.entryPoint, g.nodes, g.edges, g.conditionalEdges, g.sendEdges, g.pathMaps)
+}
 
-		if !foundNext {
-			return nil, fmt.Errorf("%w: %s", ErrNoOutgoingEdge, currentNode)
-		}
+func (g *TypedStateGraph[S]) structure() graphStructure[S] {
+	return graphStructure[S]{
+		edges:            g.edges,
+		conditionalEdges: g.conditionalEdges,
+		sendEdges:        g.sendEdges,
+		pathMaps:         g.pathMaps,
+		entryPoint:       g.entryPoint,
+		schema:           g.schema,
+		merge:            g.merge,
+		retryPolicy:      g.retryPolicy,
 	}
-
-	return state, nil
 }
 
-// executeNodeWithRetry executes a node with retry logic based on the retry policy
-func (r *StateRunnable) executeNodeWithRetry(ctx context.Context, node Node, state interface{}) (interface{}, error) {
-	var lastErr error
+// Compile compiles the state graph and returns a TypedStateRunnable instance.
+// It returns a *ValidationError if the structure of the graph is invalid (see Validate), or an
+// error if an option refers to an unknown node.
+func (g *TypedStateGraph[S]) Compile(opts ...CompileOption) (*TypedStateRunnable[S], error) {
+	if err := g.topology().check(); err != nil {
+		return nil, err
+	}
 
-	maxRetries := 1 // Default: no retries
-	if r.graph.retryPolicy != nil {
-		maxRetries = r.graph.retryPolicy.MaxRetries + 1 // +1 for initial attempt
+	options, err := newCompileOptions(g.nodes, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		result, err := node.Function(ctx, state)
-		if err == nil {
-			return result, nil
-		}
+	return &TypedStateRunnable[S]{
+		graph:   g,
+		nodes:   withMiddleware(g.nodes, g.middleware),
+		options: options,
+	}, nil
+}
 
-		lastErr = err
-
-		// Check if error is retryable
-		if r.graph.retryPolicy != nil && attempt < maxRetries-1 {
-			if r.isRetryableError(err) {
-				// Apply backoff strategy
-				delay := r.calculateBackoffDelay(attempt)
-				if delay > 0 {
-					select {
-					case <-time.After(delay):
-						// Continue with retry after delay
-					case <-ctx.Done():
-						// Context cancelled, return immediately
-						return nil, ctx.Err()
-					}
-				}
-				continue
-			}
-		}
+// SetTracer sets a tracer for observability
+func (r *TypedStateRunnable[S]) SetTracer(tracer *Tracer) {
+	r.tracer = tracer
+}
 
-		// If not retryable or max retries reached, return error
-		break
-	}
+// Invoke executes the compiled state graph with the given input state.
+// Nodes triggered by the same step run concurrently and their updates are merged before the next step.
+func (r *TypedStateRunnable[S]) Invoke(ctx context.Context, initialState S) (S, error) {
+	return r.InvokeWithConfig(ctx, initialState, nil)
+}
 
-	return nil, lastErr
+// InvokeWithConfig executes the compiled state graph with the given input state and config.
+// When a breakpoint is hit, the state reached so far is returned with a *GraphInterrupt.
+func (r *TypedStateRunnable[S]) InvokeWithConfig(ctx context.Context, initialState S, config *Config) (S, error) {
+	ctx, cancel := withConfig(ctx, config)
+	defer cancel()
+
+	return newSuperstepEngine(r.graph.structure(), r.nodes, r.tracer, r.options, config).start(ctx, initialState, config)
 }
 
-// isRetryableError checks if an error is retryable based on the retry policy
-func (r *StateRunnable) isRetryableError(err error) bool {
-	if r.graph.retryPolicy == nil {
+// retryable reports whether the policy allows retrying a node that failed with the given error.
+// A node that called Interrupt is never retried.
+func (p *RetryPolicy) retryable(err error) bool {
+	if errors.Is(err, ErrGraphInterrupted) {
 		return false
 	}
 
 	errorStr := err.Error()
-	for _, retryablePattern := range r.graph.retryPolicy.RetryableErrors {
+	for _, retryablePattern := range p.RetryableErrors {
 		if contains(errorStr, retryablePattern) {
 			return true
 		}
@@ -221,15 +248,11 @@ func findSubstring(s, substr string) bool {
 	return false
 }
 
-// calculateBackoffDelay calculates the delay for retry based on the backoff strategy
-func (r *StateRunnable) calculateBackoffDelay(attempt int) time.Duration {
-	if r.graph.retryPolicy == nil {
-		return 0
-	}
-
+// backoff calculates the delay before a retry based on the backoff strategy
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
 	baseDelay := time.Second // Default 1 second base delay
 
-	switch r.graph.retryPolicy.BackoffStrategy {
+	switch p.BackoffStrategy {
 	case FixedBackoff:
 		return baseDelay
 	case ExponentialBackoff:
@@ -243,6 +266,95 @@ func (r *StateRunnable) calculateBackoffDelay(attempt int) time.Duration {
 	}
 }
 
+// StateGraph represents a state-based graph with untyped state.
+// It is a thin wrapper over TypedStateGraph[interface{}].
+type StateGraph struct {
+	*TypedStateGraph[interface{}]
+}
+
+// NewStateGraph creates a new instance of StateGraph
+func NewStateGraph() *StateGraph {
+	return &StateGraph{
+		TypedStateGraph: NewTypedStateGraph[interface{}](),
+	}
+}
+
+// SetSchema sets a map-based state schema, such as a MapSchema with per-key reducers.
+// The state of the graph must then be a map[string]interface{}, and nodes return
+// maps holding only the keys they update.
+func (g *StateGraph) SetSchema(schema StateSchema[map[string]interface{}]) {
+	g.TypedStateGraph.SetSchema(untypedMapSchema{schema: schema})
+}
+
+// AddParallelNodes adds a set of nodes that execute in parallel as a single node.
+// The partial updates of the parallel nodes are combined through the graph's schema,
+// so the group returns one update instead of a slice of results.
+func (g *StateGraph) AddParallelNodes(groupName string, nodes map[string]func(context.Context, interface{}) (interface{}, error)) {
+	// Sort node names so that order-sensitive reducers are deterministic
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parallelNodes := make([]Node, 0, len(nodes))
+	for _, name := range names {
+		parallelNodes = append(parallelNodes, Node{
+			Name:     name,
+			Function: nodes[name],
+		})
+	}
+
+	g.AddNode(groupName, func(ctx context.Context, state interface{}) (interface{}, error) {
+		// The schema is resolved at run time so it may be set after the node is added
+		return NewParallelNode(groupName, parallelNodes...).WithSchema(g.schema).Execute(ctx, state)
+	})
+}
+
+// AddMapReduceNode adds a map-reduce pattern node. The reducer receives the results of the
+// map nodes; without one, their partial updates are combined through the graph's schema,
+// like those of AddParallelNodes.
+func (g *StateGraph) AddMapReduceNode(
+	name string,
+	mapFunctions map[string]func(context.Context, interface{}) (interface{}, error),
+	reducer func([]interface{}) (interface{}, error),
+) {
+	// Sort node names so that order-sensitive reducers are deterministic
+	names := make([]string, 0, len(mapFunctions))
+	for nodeName := range mapFunctions {
+		names = append(names, nodeName)
+	}
+	sort.Strings(names)
+
+	mapNodes := make([]Node, 0, len(mapFunctions))
+	for _, nodeName := range names {
+		mapNodes = append(mapNodes, Node{
+			Name:     nodeName,
+			Function: mapFunctions[nodeName],
+		})
+	}
+
+	g.AddNode(name, func(ctx context.Context, state interface{}) (interface{}, error) {
+		// The schema is resolved at run time so it may be set after the node is added
+		return NewMapReduceNode(name, reducer, mapNodes...).WithSchema(g.schema).Execute(ctx, state)
+	})
+}
+
+// StateRunnable represents a compiled state graph that can be invoked
+type StateRunnable struct {
+	*TypedStateRunnable[interface{}]
+}
+
+// Compile compiles the state graph and returns a StateRunnable instance
+func (g *StateGraph) Compile(opts ...CompileOption) (*StateRunnable, error) {
+	runnable, err := g.TypedStateGraph.Compile(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StateRunnable{TypedStateRunnable: runnable}, nil
+}
+
 // ListenableStateGraph extends StateGraph with listener capabilities
 type ListenableStateGraph struct {
 	*StateGraph
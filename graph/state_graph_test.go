@@ -0,0 +1,74 @@
+package graph_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+type documentState struct {
+	Text     string
+	Approved bool
+}
+
+func TestTypedStateGraph(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewTypedStateGraph[documentState]()
+	g.AddNode("draft", func(_ context.Context, state documentState) (documentState, error) {
+		state.Text = "draft"
+		return state, nil
+	})
+	g.AddNode("review", func(_ context.Context, state documentState) (documentState, error) {
+		state.Approved = state.Text == "draft"
+		return state, nil
+	})
+	g.AddEdge("draft", "review")
+	g.AddConditionalEdge("review", func(_ context.Context, state documentState) string {
+		if state.Approved {
+			return graph.END
+		}
+		return "draft"
+	})
+	g.SetEntryPoint("draft")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), documentState{})
+	if err != nil {
+		t.Fatalf("unexpected invoke error: %v", err)
+	}
+
+	if !result.Approved || result.Text != "draft" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestStateGraph_UntypedWrapper(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewStateGraph()
+	g.AddNode("upper", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state.(string) + "!", nil
+	})
+	g.AddEdge("upper", graph.END)
+	g.SetEntryPoint("upper")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("unexpected invoke error: %v", err)
+	}
+
+	if result != "hello!" {
+		t.Errorf("expected 'hello!', got %v", result)
+	}
+}
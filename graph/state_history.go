@@ -0,0 +1,189 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// StateSnapshot describes the state of a thread at one of its checkpoints
+type StateSnapshot struct {
+	// Values is the state saved in the checkpoint
+	Values interface{}
+
+	// NodeName is the node that produced the state
+	NodeName string
+
+	// Next lists the nodes that run when execution continues from this checkpoint.
+	// It is empty when the run finished here.
+	Next []string
+
+	// ThreadID is the thread the checkpoint belongs to
+	ThreadID string
+
+	// CheckpointID identifies the checkpoint
+	CheckpointID string
+
+	// ParentID identifies the checkpoint saved before this one
+	ParentID string
+
+	// Version is the position of the checkpoint in its thread, starting at 1
+	Version int
+
+	// Metadata is the metadata of the checkpoint
+	Metadata map[string]interface{}
+
+	// CreatedAt is when the checkpoint was saved
+	CreatedAt time.Time
+}
+
+// GetState returns a snapshot of the latest checkpoint of a thread
+func (cr *CheckpointableRunnable) GetState(ctx context.Context, threadID string) (*StateSnapshot, error) {
+	head, err := cr.head(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, fmt.Errorf("no checkpoints found for thread %s", threadID)
+	}
+
+	return cr.snapshot(ctx, head), nil
+}
+
+// GetStateHistory returns snapshots of every checkpoint of a thread, newest first
+func (cr *CheckpointableRunnable) GetStateHistory(ctx context.Context, threadID string) ([]*StateSnapshot, error) {
+	checkpoints, err := cr.history(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]*StateSnapshot, len(checkpoints))
+	for i, checkpoint := range checkpoints {
+		snapshots[i] = cr.snapshot(ctx, checkpoint)
+	}
+
+	return snapshots, nil
+}
+
+// UpdateState creates a new checkpoint in a thread as a child of the given checkpoint, or of
+// the latest one when checkpointID is empty, holding that checkpoint's state with the patch
+// applied. A map patch is merged into a map state key by key; any other patch replaces the
+// state. With asNode set, the new checkpoint behaves as if asNode had produced the state, so
+// execution continues with the nodes that follow asNode; otherwise it continues where the
+// original checkpoint would have, including its sends. The writes of tasks that completed in
+// a failed step are kept only when the patch leaves the state unchanged, since they were
+// produced from that state. The original checkpoints are left untouched, so updating
+// a past checkpoint forks the thread, and invoking the thread with a nil input continues
+// from the new checkpoint.
+func (cr *CheckpointableRunnable) UpdateState(ctx context.Context, threadID, checkpointID string, patch interface{}, asNode string) (*StateSnapshot, error) {
+	head, err := cr.head(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	base := head
+	if checkpointID != "" {
+		base, err = cr.LoadCheckpoint(ctx, checkpointID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+		}
+		if checkpointThreadID(base) != threadID {
+			return nil, fmt.Errorf("checkpoint %s does not belong to thread %s", checkpointID, threadID)
+		}
+	}
+	if base == nil {
+		return nil, fmt.Errorf("no checkpoints found for thread %s", threadID)
+	}
+
+	if asNode != "" {
+		if _, ok := cr.runnable.graph.nodes[asNode]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, asNode)
+		}
+	}
+
+	writer := &checkpointWriter{
+		store:    cr.config.Store,
+		threadID: threadID,
+		parentID: base.ID,
+		version:  head.Version,
+	}
+
+	state := applyStatePatch(base.State, patch)
+	checkpoint := writer.next(base.NodeName, state, map[string]interface{}{
+		"event": "update",
+	})
+	if asNode != "" {
+		checkpoint.NodeName = asNode
+		checkpoint.Metadata["as_node"] = asNode
+	} else {
+		checkpoint.Next = base.Next
+		checkpoint.Sends = base.Sends
+		if reflect.DeepEqual(state, base.State) {
+			checkpoint.PendingWrites = base.PendingWrites
+		}
+	}
+
+	if err := cr.config.Store.Save(ctx, checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	if err := cr.prune(ctx, threadID); err != nil {
+		return nil, err
+	}
+
+	return cr.snapshot(ctx, checkpoint), nil
+}
+
+// applyStatePatch returns the state with the patch applied, without modifying the state
+func applyStatePatch(state, patch interface{}) interface{} {
+	stateMap, stateIsMap := state.(map[string]interface{})
+	patchMap, patchIsMap := patch.(map[string]interface{})
+	if !stateIsMap || !patchIsMap {
+		return patch
+	}
+
+	patched := make(map[string]interface{}, len(stateMap)+len(patchMap))
+	for key, value := range stateMap {
+		patched[key] = value
+	}
+	for key, value := range patchMap {
+		patched[key] = value
+	}
+	return patched
+}
+
+// snapshot describes a checkpoint, working out which nodes follow it
+func (cr *CheckpointableRunnable) snapshot(ctx context.Context, checkpoint *Checkpoint) *StateSnapshot {
+	return &StateSnapshot{
+		Values:       checkpoint.State,
+		NodeName:     checkpoint.NodeName,
+		Next:         cr.nextNodes(ctx, checkpoint),
+		ThreadID:     checkpointThreadID(checkpoint),
+		CheckpointID: checkpoint.ID,
+		ParentID:     checkpoint.ParentID,
+		Version:      checkpoint.Version,
+		Metadata:     checkpoint.Metadata,
+		CreatedAt:    checkpoint.Timestamp,
+	}
+}
+
+// nextNodes returns the nodes that run after a checkpoint: the pending nodes of an
+// interrupted run, or else the targets of the checkpointed node's outgoing edges.
+// Checkpoints of nodes that are not part of the graph have no next nodes.
+func (cr *CheckpointableRunnable) nextNodes(ctx context.Context, checkpoint *Checkpoint) []string {
+	engine := cr.runnable.newEngine(nil)
+	if checkpoint.pending() {
+		return engine.schedule(append(append([]string(nil), checkpoint.Next...), sendNodes(checkpoint.Sends)...))
+	}
+
+	if _, ok := engine.nodes[checkpoint.NodeName]; !ok {
+		return nil
+	}
+
+	next, err := engine.successors(ctx, checkpoint.NodeName, checkpoint.State)
+	if err != nil {
+		return nil
+	}
+
+	return engine.schedule(append(next.nodes, sendNodes(next.sends)...))
+}
@@ -0,0 +1,254 @@
+package graph_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+func threadConfig(threadID string) *graph.Config {
+	return &graph.Config{
+		Configurable: map[string]interface{}{"thread_id": threadID},
+	}
+}
+
+func TestCheckpointableRunnable_ThreadHistory(t *testing.T) {
+	t.Parallel()
+
+	runnable, err := newReviewGraph().CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	for _, input := range []string{"first", "second"} {
+		if _, err := runnable.InvokeWithConfig(ctx, input, threadConfig("thread-"+input)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	history, err := runnable.GetStateHistory(ctx, "thread-first")
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 checkpoints in thread, got %d", len(history))
+	}
+
+	latest, previous := history[0], history[1]
+	if latest.NodeName != "publish" || previous.NodeName != "draft" {
+		t.Errorf("expected newest first, got %s then %s", latest.NodeName, previous.NodeName)
+	}
+	if latest.ParentID != previous.CheckpointID || previous.ParentID != "" {
+		t.Errorf("unexpected lineage: %q -> %q -> %q", latest.CheckpointID, latest.ParentID, previous.ParentID)
+	}
+	if latest.Version != 2 || previous.Version != 1 {
+		t.Errorf("expected versions 2 and 1, got %d and %d", latest.Version, previous.Version)
+	}
+	if len(latest.Next) != 0 || fmt.Sprint(previous.Next) != "[publish]" {
+		t.Errorf("unexpected next nodes: %v and %v", latest.Next, previous.Next)
+	}
+	if latest.Values != "first draft published" || latest.ThreadID != "thread-first" {
+		t.Errorf("unexpected snapshot: %+v", latest)
+	}
+
+	// A second invocation of the thread continues its lineage
+	if _, err := runnable.InvokeWithConfig(ctx, "again", threadConfig("thread-first")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	history, err = runnable.GetStateHistory(ctx, "thread-first")
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	if len(history) != 4 || history[1].ParentID != latest.CheckpointID || history[0].Version != 4 {
+		t.Errorf("expected the second run to extend the thread, got %d checkpoints", len(history))
+	}
+
+	other, err := runnable.GetStateHistory(ctx, "thread-second")
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	if len(other) != 2 {
+		t.Errorf("expected threads to be kept apart, got %d checkpoints", len(other))
+	}
+}
+
+func TestCheckpointableRunnable_GetState(t *testing.T) {
+	t.Parallel()
+
+	runnable, err := newReviewGraph().CompileCheckpointable(graph.WithInterruptBefore("publish"))
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := runnable.GetState(ctx, "review"); err == nil {
+		t.Error("expected error for a thread without checkpoints")
+	}
+
+	if _, err := runnable.InvokeWithConfig(ctx, "post", threadConfig("review")); err == nil {
+		t.Fatal("expected the run to be interrupted")
+	}
+
+	state, err := runnable.GetState(ctx, "review")
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+	if state.Values != "post draft" || fmt.Sprint(state.Next) != "[publish]" {
+		t.Errorf("unexpected state: %+v", state)
+	}
+
+	if _, err := runnable.Resume(ctx, state.CheckpointID); err != nil {
+		t.Fatalf("unexpected resume error: %v", err)
+	}
+
+	state, err = runnable.GetState(ctx, "review")
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+	if state.Values != "post draft published" || len(state.Next) != 0 || state.Version != 3 {
+		t.Errorf("unexpected state after resume: %+v", state)
+	}
+}
+
+func TestCheckpointableRunnable_UpdateStateForksThread(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewCheckpointableMessageGraph()
+	g.AddNode("plan", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state.(int) + 1, nil
+	})
+	g.AddNode("act", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state.(int) * 10, nil
+	})
+	g.AddEdge("plan", "act")
+	g.AddEdge("act", graph.END)
+	g.SetEntryPoint("plan")
+
+	runnable, err := g.CompileCheckpointable()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	config := threadConfig("debug")
+	if result, err := runnable.InvokeWithConfig(ctx, 1, config); err != nil || result != 20 {
+		t.Fatalf("expected 20, got %v (%v)", result, err)
+	}
+
+	history, err := runnable.GetStateHistory(ctx, "debug")
+	if err != nil || len(history) != 2 {
+		t.Fatalf("expected 2 checkpoints, got %d (%v)", len(history), err)
+	}
+	original, planned := history[0], history[1]
+
+	// Edit the decision made by plan and continue from there
+	forked, err := runnable.UpdateState(ctx, "debug", planned.CheckpointID, 5, "")
+	if err != nil {
+		t.Fatalf("failed to update state: %v", err)
+	}
+	if forked.ParentID != planned.CheckpointID || fmt.Sprint(forked.Next) != "[act]" {
+		t.Errorf("unexpected forked checkpoint: %+v", forked)
+	}
+
+	result, err := runnable.InvokeWithConfig(ctx, nil, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 50 {
+		t.Errorf("expected the fork to continue with the edited state, got %v", result)
+	}
+
+	// The original branch is left intact and can be replayed
+	loaded, err := runnable.LoadCheckpoint(ctx, original.CheckpointID)
+	if err != nil || loaded.State != 20 {
+		t.Errorf("expected original checkpoint to be kept, got %v (%v)", loaded, err)
+	}
+
+	result, err = runnable.InvokeWithConfig(ctx, nil, &graph.Config{
+		Configurable: map[string]interface{}{
+			"thread_id":     "debug",
+			"checkpoint_id": planned.CheckpointID,
+		},
+	})
+	if err != nil || result != 20 {
+		t.Errorf("expected replay from plan to produce 20, got %v (%v)", result, err)
+	}
+
+	// Updating as the last node leaves nothing to run
+	skipped, err := runnable.UpdateState(ctx, "debug", "", 7, "act")
+	if err != nil {
+		t.Fatalf("failed to update state: %v", err)
+	}
+	if len(skipped.Next) != 0 {
+		t.Errorf("expected no next nodes, got %v", skipped.Next)
+	}
+	if result, err := runnable.InvokeWithConfig(ctx, nil, config); err != nil || result != 7 {
+		t.Errorf("expected 7, got %v (%v)", result, err)
+	}
+
+	if _, err := runnable.UpdateState(ctx, "debug", "", 1, "missing"); err == nil {
+		t.Error("expected error for unknown node")
+	}
+}
+
+func TestCheckpointableRunnable_UpdateStateKeepsSends(t *testing.T) {
+	t.Parallel()
+
+	var runs int32
+	g := graph.NewCheckpointableMessageGraph()
+	g.AddNode("plan", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	g.AddNode("work", func(_ context.Context, state interface{}) (interface{}, error) {
+		atomic.AddInt32(&runs, 1)
+		return state.(int) * state.(int), nil
+	})
+	g.AddSendEdge("plan", func(_ context.Context, state interface{}) []graph.Send {
+		var sends []graph.Send
+		for i := 1; i <= state.(int); i++ {
+			sends = append(sends, graph.Send{Node: "work", State: i})
+		}
+		return sends
+	})
+	g.AddEdge("work", graph.END)
+	g.SetEntryPoint("plan")
+	g.SetStateMerger(func(_ context.Context, _ interface{}, updates []interface{}) (interface{}, error) {
+		sum := 0
+		for _, update := range updates {
+			sum += update.(int)
+		}
+		return sum, nil
+	})
+
+	runnable, err := g.CompileCheckpointable(graph.WithInterruptBefore("work"))
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	ctx := context.Background()
+	config := threadConfig("edit-sends")
+	if _, err := runnable.InvokeWithConfig(ctx, 3, config); err == nil {
+		t.Fatal("expected the run to pause before work")
+	}
+
+	edited, err := runnable.UpdateState(ctx, "edit-sends", "", 100, "")
+	if err != nil {
+		t.Fatalf("failed to update state: %v", err)
+	}
+	if fmt.Sprint(edited.Next) != "[work]" {
+		t.Errorf("expected work to be next, got %v", edited.Next)
+	}
+
+	result, err := runnable.InvokeWithConfig(ctx, nil, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 14 || atomic.LoadInt32(&runs) != 3 {
+		t.Errorf("expected the sent work to run 3 times and sum to 14, got %v after %d runs", result, runs)
+	}
+}
@@ -2,6 +2,7 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
 )
 
@@ -26,10 +27,16 @@ func NewSubgraph(name string, graph *MessageGraph) (*Subgraph, error) {
 	}, nil
 }
 
-// Execute runs the subgraph as a node
+// Execute runs the subgraph as a node.
+// A command for the parent graph returned by a node of the subgraph ends the subgraph,
+// and is returned for the parent graph to apply.
 func (s *Subgraph) Execute(ctx context.Context, state interface{}) (interface{}, error) {
 	result, err := s.runnable.Invoke(ctx, state)
 	if err != nil {
+		var parent *parentCommandError
+		if errors.As(err, &parent) {
+			return &Command{Update: parent.command.Update, Goto: parent.command.Goto}, nil
+		}
 		return nil, fmt.Errorf("subgraph %s execution failed: %w", s.name, err)
 	}
 	return result, nil
@@ -0,0 +1,674 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrInvalidConcurrentUpdate is returned when several nodes update the state in the
+// same superstep and the graph has no way to merge their updates.
+var ErrInvalidConcurrentUpdate = errors.New("multiple nodes updated the state in the same step without a merge function")
+
+// StateMergeFunc combines the states returned by every node that ran in one superstep.
+// Updates are passed in scheduling order, which follows the order in which edges were added.
+type StateMergeFunc[S any] func(ctx context.Context, current S, updates []S) (S, error)
+
+// superstepEngine executes a graph as a sequence of supersteps, in the style of Pregel.
+// Every node triggered by the previous step runs concurrently, their updates are merged
+// into a single state, and the outgoing edges of all of them determine the next step.
+type superstepEngine[S any] struct {
+	nodes            map[string]TypedNode[S]
+	edges            []Edge
+	conditionalEdges map[string]func(ctx context.Context, state S) string
+	sendEdges        map[string]func(ctx context.Context, state S) []Send
+	entryPoint       string
+
+	// pathMaps translates the values returned by conditional edges into node names
+	pathMaps map[string]map[string]string
+
+	// schema folds node updates into the state through per-key reducers
+	schema StateSchema[S]
+
+	// merge combines the updates of a step with more than one node when there is no schema
+	merge StateMergeFunc[S]
+
+	// hooks plug the features of the runnable into the run; the first hook wraps the others
+	hooks []runHook[S]
+
+	// runNode executes a single node through the hooks
+	runNode nodeRunner[S]
+
+	// maxConcurrency bounds the number of tasks of a step that run at once; 0 means no bound
+	maxConcurrency int
+
+	// recursionLimit bounds the number of steps of a run; 0 means DefaultRecursionLimit
+	recursionLimit int
+
+	// interruptBefore and interruptAfter are the nodes at which execution pauses
+	interruptBefore map[string]bool
+	interruptAfter  map[string]bool
+
+	// resumeValues are returned by Interrupt calls in the first step of a resumed run,
+	// keyed by the ID of the task that called Interrupt
+	resumeValues map[string][]interface{}
+
+	// pendingWrites are the outputs of the tasks that completed in the first step of a
+	// resumed run before it failed; those tasks are not run again
+	pendingWrites []PendingWrite
+
+	// onStepError is called when a node fails, with the state the step started from, the
+	// nodes and sends of the step, and the writes of the tasks that completed in it
+	onStepError func(ctx context.Context, state S, active []string, sends []Send, failed string, writes []PendingWrite)
+}
+
+// graphStructure is the part of a graph that the superstep engine runs: its edges and
+// entry point, how the updates of a step are combined and how failed nodes are retried
+type graphStructure[S any] struct {
+	edges            []Edge
+	conditionalEdges map[string]func(ctx context.Context, state S) string
+	sendEdges        map[string]func(ctx context.Context, state S) []Send
+	pathMaps         map[string]map[string]string
+	entryPoint       string
+	schema           StateSchema[S]
+	merge            StateMergeFunc[S]
+	retryPolicy      *RetryPolicy
+}
+
+// newSuperstepEngine creates the engine for one invocation of a compiled graph with the
+// given nodes. The given hooks wrap those for the tracer, the graph's retry policy and the
+// callbacks of the config.
+func newSuperstepEngine[S any](graph graphStructure[S], nodes map[string]TypedNode[S], tracer *Tracer, options compileOptions, config *Config, hooks ...runHook[S]) *superstepEngine[S] {
+	interruptBefore, interruptAfter := options.interrupts(config)
+
+	return &superstepEngine[S]{
+		nodes:            nodes,
+		edges:            graph.edges,
+		conditionalEdges: graph.conditionalEdges,
+		sendEdges:        graph.sendEdges,
+		pathMaps:         graph.pathMaps,
+		entryPoint:       graph.entryPoint,
+		schema:           graph.schema,
+		merge:            graph.merge,
+		maxConcurrency:   config.maxConcurrency(),
+		recursionLimit:   config.recursionLimit(),
+		hooks:            append(hooks, runHooks[S](config, tracer, graph.retryPolicy)...),
+		interruptBefore:  interruptBefore,
+		interruptAfter:   interruptAfter,
+	}
+}
+
+// task is one execution of a node in a superstep. Nodes triggered by edges read the state
+// of the graph; nodes reached through a Send read the state they were sent.
+type task[S any] struct {
+	node  string
+	input S
+
+	// id tells the executions of a node in the same step apart
+	id string
+}
+
+// nodeResult holds the outcome of one task within a superstep
+type nodeResult[S any] struct {
+	state     S
+	err       error
+	recovered interface{}
+}
+
+// routing lists the nodes and sends a node leads to
+type routing struct {
+	nodes []string
+	sends []Send
+}
+
+// invoke runs the graph from the entry point until no nodes remain to be executed
+func (e *superstepEngine[S]) invoke(ctx context.Context, input S) (S, error) {
+	state := input
+	if e.schema != nil {
+		// The input is applied as the first update to the schema's initial state
+		var err error
+		state, err = e.schema.Update(e.schema.Init(), input)
+		if err != nil {
+			var zero S
+			return zero, fmt.Errorf("failed to apply input to state schema: %w", err)
+		}
+	}
+
+	return e.run(ctx, state, []string{e.entryPoint}, nil, false)
+}
+
+// start runs the graph for the given config: from the nodes in Config.ResumeFrom when
+// they are set, treating the input as the complete state, or else from the entry point
+func (e *superstepEngine[S]) start(ctx context.Context, input S, config *Config) (S, error) {
+	if config != nil && len(config.ResumeFrom) > 0 {
+		return e.resume(ctx, input, config.ResumeFrom, nil)
+	}
+	return e.invoke(ctx, input)
+}
+
+// resumeAfter continues a run whose last completed node was the given node, starting
+// with the nodes its outgoing edges lead to for the given state
+func (e *superstepEngine[S]) resumeAfter(ctx context.Context, state S, node string) (S, error) {
+	if _, ok := e.nodes[node]; !ok {
+		var zero S
+		return zero, fmt.Errorf("%w: %s", ErrNodeNotFound, node)
+	}
+
+	next, err := e.successors(ctx, node, state)
+	if err != nil {
+		var zero S
+		return zero, err
+	}
+
+	return e.run(ctx, state, next.nodes, next.sends, false)
+}
+
+// continueWith continues a run from the given nodes and sends with the given state, as
+// if the step that scheduled them had just completed
+func (e *superstepEngine[S]) continueWith(ctx context.Context, state S, next []string, sends []Send) (S, error) {
+	return e.run(ctx, state, next, sends, false)
+}
+
+// resume continues a paused run from the given nodes and sends with the given state.
+// Breakpoints set before those nodes are skipped, since the run already paused there.
+func (e *superstepEngine[S]) resume(ctx context.Context, state S, next []string, sends []Send) (S, error) {
+	return e.run(ctx, state, next, sends, true)
+}
+
+// run executes supersteps starting with the given nodes and sends. When a breakpoint is
+// hit it returns the current state together with a *GraphInterrupt.
+func (e *superstepEngine[S]) run(ctx context.Context, state S, start []string, sends []Send, resuming bool) (result S, err error) {
+	for _, hook := range e.hooks {
+		ctx = hook.startRun(ctx, state)
+	}
+	defer func() {
+		// A node panic is reported as the error of the run before it propagates
+		recovered := recover()
+		if recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+		for i := len(e.hooks) - 1; i >= 0; i-- {
+			e.hooks[i].endRun(ctx, result, err)
+		}
+		if recovered != nil {
+			panic(recovered)
+		}
+	}()
+
+	e.runNode = func(ctx context.Context, node TypedNode[S], state S) (S, error) {
+		return node.Function(ctx, state)
+	}
+	for i := len(e.hooks) - 1; i >= 0; i-- {
+		e.runNode = e.hooks[i].wrapNode(e.runNode)
+	}
+
+	return e.steps(ctx, state, start, sends, resuming)
+}
+
+// steps executes the supersteps of a run
+func (e *superstepEngine[S]) steps(ctx context.Context, state S, start []string, sends []Send, resuming bool) (S, error) {
+	var zero S
+
+	limit := e.recursionLimit
+	if limit <= 0 {
+		limit = DefaultRecursionLimit
+	}
+
+	// visited holds the last nodes that ran, to name them if the recursion limit is reached
+	var visited []string
+
+	active := e.schedule(start)
+	for step := 0; len(active) > 0 || len(sends) > 0; step++ {
+		if step >= limit {
+			return zero, &RecursionLimitError{Limit: limit, Nodes: visited}
+		}
+
+		// Nodes that ignore their context still stop the run at the next step
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+
+		tasks, err := e.tasks(active, sends, state)
+		if err != nil {
+			return zero, err
+		}
+
+		if !resuming || step > 0 {
+			for _, t := range tasks {
+				if e.interruptBefore[t.node] {
+					return state, e.interrupt(t.node, true, state, active, sends)
+				}
+			}
+		}
+
+		var (
+			resumeValues  map[string][]interface{}
+			pendingWrites []PendingWrite
+		)
+		if resuming && step == 0 {
+			resumeValues = e.resumeValues
+			pendingWrites = e.pendingWrites
+		}
+
+		for _, t := range tasks {
+			visited = append(visited, t.node)
+		}
+		if len(visited) > recentNodes {
+			visited = append([]string(nil), visited[len(visited)-recentNodes:]...)
+		}
+
+		updates, err := e.runStep(withRemainingSteps(ctx, limit-step), tasks, resumeValues, pendingWrites)
+		if err != nil {
+			var interrupt *GraphInterrupt
+			if errors.As(err, &interrupt) {
+				// The interrupted step runs again when the run is resumed, except for the
+				// tasks whose writes were saved with the interrupt
+				interrupt.State = state
+				interrupt.Next = append([]string(nil), active...)
+				interrupt.Sends = append([]Send(nil), sends...)
+				return state, interrupt
+			}
+
+			var failure *stepFailure
+			if errors.As(err, &failure) {
+				if e.onStepError != nil && len(failure.writes) > 0 {
+					e.onStepError(ctx, state, active, sends, failure.node, failure.writes)
+				}
+				err = failure.err
+			}
+			return zero, err
+		}
+
+		updates, routes, err := e.resolveCommands(tasks, updates)
+		if err != nil {
+			return zero, err
+		}
+
+		state, err = e.mergeUpdates(ctx, state, updates)
+		if err != nil {
+			return zero, err
+		}
+
+		executed := tasks
+		active, sends, err = e.nextTasks(ctx, executed, state, routes)
+		if err != nil {
+			return zero, err
+		}
+
+		if err := e.endStep(ctx, executed, state, active, sends); err != nil {
+			return zero, err
+		}
+
+		// There is nothing left to resume once the run reaches END
+		if len(active) > 0 || len(sends) > 0 {
+			for _, t := range executed {
+				if e.interruptAfter[t.node] {
+					return state, e.interrupt(t.node, false, state, active, sends)
+				}
+			}
+		}
+	}
+
+	return state, nil
+}
+
+// interrupt builds the error returned when execution pauses at a breakpoint
+func (e *superstepEngine[S]) interrupt(node string, before bool, state S, next []string, sends []Send) *GraphInterrupt {
+	return &GraphInterrupt{
+		Node:   node,
+		Before: before,
+		State:  state,
+		Next:   append([]string(nil), next...),
+		Sends:  append([]Send(nil), sends...),
+	}
+}
+
+// tasks builds the tasks of a step: one for each active node, and one for each send
+func (e *superstepEngine[S]) tasks(active []string, sends []Send, state S) ([]task[S], error) {
+	tasks := make([]task[S], 0, len(active)+len(sends))
+
+	for _, name := range active {
+		if _, ok := e.nodes[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, name)
+		}
+		tasks = append(tasks, task[S]{node: name, input: state, id: name})
+	}
+
+	for i, send := range sends {
+		if _, ok := e.nodes[send.Node]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, send.Node)
+		}
+
+		var input S
+		if send.State != nil {
+			var ok bool
+			if input, ok = send.State.(S); !ok {
+				return nil, fmt.Errorf("state sent to node %s has type %T, not the state type", send.Node, send.State)
+			}
+		}
+		tasks = append(tasks, task[S]{node: send.Node, input: input, id: fmt.Sprintf("%s:%d", send.Node, i)})
+	}
+
+	return tasks, nil
+}
+
+// stepFailure is returned by runStep when a node fails, along with the writes of the
+// tasks that completed in the step
+type stepFailure struct {
+	err    error
+	node   string
+	writes []PendingWrite
+}
+
+func (f *stepFailure) Error() string { return f.err.Error() }
+
+func (f *stepFailure) Unwrap() error { return f.err }
+
+// runStep executes all tasks of a step and returns their updates in scheduling order.
+// A single task runs on the calling goroutine; more run concurrently, at most
+// maxConcurrency at a time. If a node calls Interrupt, a *GraphInterrupt for that node is
+// returned, carrying the writes of the tasks that completed. Tasks whose output is among
+// the pending writes are not run again.
+func (e *superstepEngine[S]) runStep(ctx context.Context, tasks []task[S], resumeValues map[string][]interface{}, pendingWrites []PendingWrite) ([]S, error) {
+	results := make([]nodeResult[S], len(tasks))
+	scratches := make([]*pendingWriteScratch, len(tasks))
+
+	runTask := func(idx int) {
+		t := tasks[idx]
+		scratches[idx] = newPendingWriteScratch(t.id, pendingWrites)
+
+		// A saved output that no longer has the state's type, e.g. after a JSON round
+		// trip, is discarded and the node runs again
+		if value, ok := scratches[idx].lookup(""); ok {
+			if update, ok := value.(S); ok {
+				results[idx].state = update
+				return
+			}
+		}
+
+		taskCtx := withPendingWrites(ctx, scratches[idx])
+		results[idx].state, results[idx].err = e.execute(taskCtx, t.node, t.input, resumeValues[t.id])
+	}
+
+	if len(tasks) == 1 {
+		runTask(0)
+	} else {
+		var limit chan struct{}
+		if e.maxConcurrency > 0 {
+			limit = make(chan struct{}, e.maxConcurrency)
+		}
+
+		var wg sync.WaitGroup
+		for i := range tasks {
+			wg.Add(1)
+			go func(idx int) {
+				defer wg.Done()
+
+				if limit != nil {
+					limit <- struct{}{}
+					defer func() { <-limit }()
+				}
+
+				// Capture panics so they can be re-raised on the caller's goroutine
+				defer func() {
+					if r := recover(); r != nil {
+						results[idx].recovered = r
+					}
+				}()
+
+				runTask(idx)
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	// Failures take precedence over interrupts raised by other nodes of the step
+	var (
+		interrupt *GraphInterrupt
+		failure   *stepFailure
+		writes    []PendingWrite
+	)
+	updates := make([]S, len(tasks))
+	for i, res := range results {
+		if res.recovered != nil {
+			panic(res.recovered)
+		}
+
+		node := tasks[i].node
+
+		var nodeInterrupt *GraphInterrupt
+		if errors.As(res.err, &nodeInterrupt) {
+			if interrupt == nil {
+				interrupt = nodeInterrupt
+				interrupt.Node = node
+				interrupt.Before = true
+				interrupt.taskID = tasks[i].id
+				interrupt.resumeValues = resumeValues[tasks[i].id]
+			}
+			writes = append(writes, scratches[i].writes()...)
+			continue
+		}
+
+		if res.err != nil {
+			if failure == nil {
+				failure = &stepFailure{err: fmt.Errorf("error in node %s: %w", node, res.err), node: node}
+			}
+			writes = append(writes, scratches[i].writes()...)
+			continue
+		}
+
+		updates[i] = res.state
+		if write, ok := newPendingWrite(tasks[i].id, res.state); ok {
+			writes = append(writes, write)
+		}
+	}
+
+	if failure != nil {
+		failure.writes = writes
+		return nil, failure
+	}
+
+	if interrupt != nil {
+		interrupt.writes = writes
+		return nil, interrupt
+	}
+
+	return updates, nil
+}
+
+// execute runs a single node through the configured node runner. The resume values
+// are returned, in order, by the calls the node makes to Interrupt.
+func (e *superstepEngine[S]) execute(ctx context.Context, name string, state S, resumeValues []interface{}) (S, error) {
+	ctx = withInterruptScratch(ctx, resumeValues)
+
+	return e.runNode(ctx, e.nodes[name], state)
+}
+
+// resolveCommands replaces the commands returned by tasks of a step with their updates,
+// dropping those without one. It returns where each command routes to, keyed by the index
+// of the task that returned it.
+func (e *superstepEngine[S]) resolveCommands(tasks []task[S], updates []S) ([]S, map[int]routing, error) {
+	var (
+		resolved []S
+		routes   map[int]routing
+	)
+
+	for i, update := range updates {
+		command, ok := commandOf(update)
+		if !ok {
+			resolved = append(resolved, update)
+			continue
+		}
+
+		node := tasks[i].node
+		if command.Graph == CommandParent {
+			return nil, nil, &parentCommandError{node: node, command: command}
+		}
+
+		if command.Goto != nil {
+			nodes, sends, err := command.targets()
+			if err != nil {
+				return nil, nil, fmt.Errorf("error in node %s: %w", node, err)
+			}
+			if routes == nil {
+				routes = make(map[int]routing)
+			}
+			routes[i] = routing{nodes: nodes, sends: sends}
+		}
+
+		if command.Update != nil {
+			state, ok := command.Update.(S)
+			if !ok {
+				return nil, nil, fmt.Errorf("error in node %s: command update has type %T, not the state type", node, command.Update)
+			}
+			resolved = append(resolved, state)
+		}
+	}
+
+	return resolved, routes, nil
+}
+
+// mergeUpdates folds the updates of a step into the current state
+func (e *superstepEngine[S]) mergeUpdates(ctx context.Context, current S, updates []S) (S, error) {
+	if e.schema != nil {
+		state := current
+		for _, update := range updates {
+			var err error
+			state, err = e.schema.Update(state, update)
+			if err != nil {
+				var zero S
+				return zero, err
+			}
+		}
+		return state, nil
+	}
+
+	switch len(updates) {
+	case 0:
+		return current, nil
+	case 1:
+		return updates[0], nil
+	}
+
+	if e.merge == nil {
+		var zero S
+		return zero, ErrInvalidConcurrentUpdate
+	}
+
+	return e.merge(ctx, current, updates)
+}
+
+// nextTasks resolves the outgoing edges of every node executed in the step, following
+// the edges of a node once however many times it ran. Tasks that returned a command go
+// where it routes to instead.
+func (e *superstepEngine[S]) nextTasks(ctx context.Context, executed []task[S], state S, routes map[int]routing) ([]string, []Send, error) {
+	var (
+		next     []string
+		sends    []Send
+		followed = make(map[string]bool, len(executed))
+	)
+
+	for i, t := range executed {
+		route, ok := routes[i]
+		if !ok {
+			if followed[t.node] {
+				continue
+			}
+			followed[t.node] = true
+
+			var err error
+			if route, err = e.successors(ctx, t.node, state); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		for _, target := range route.nodes {
+			e.traverse(ctx, t.node, target, state)
+		}
+		for _, send := range route.sends {
+			e.traverse(ctx, t.node, send.Node, state)
+		}
+		next = append(next, route.nodes...)
+		sends = append(sends, route.sends...)
+	}
+
+	return e.schedule(next), sends, nil
+}
+
+// successors resolves the outgoing edges of a single node
+func (e *superstepEngine[S]) successors(ctx context.Context, name string, state S) (routing, error) {
+	// Conditional edges take precedence over static edges
+	if condition, ok := e.conditionalEdges[name]; ok {
+		target := condition(ctx, state)
+		if paths, ok := e.pathMaps[name]; ok {
+			mapped, ok := paths[target]
+			if !ok {
+				return routing{}, fmt.Errorf("conditional edge from %s returned %q, which is not one of its paths", name, target)
+			}
+			target = mapped
+		}
+		if target == "" {
+			return routing{}, fmt.Errorf("conditional edge returned empty next node from %s", name)
+		}
+		return routing{nodes: []string{target}}, nil
+	}
+
+	// An empty list of sends ends this branch of the run
+	if send, ok := e.sendEdges[name]; ok {
+		return routing{sends: send(ctx, state)}, nil
+	}
+
+	var targets []string
+	for _, edge := range e.edges {
+		if edge.From == name {
+			targets = append(targets, edge.To)
+		}
+	}
+
+	if len(targets) == 0 {
+		return routing{}, fmt.Errorf("%w: %s", ErrNoOutgoingEdge, name)
+	}
+
+	return routing{nodes: targets}, nil
+}
+
+// endStep reports a completed step to the hooks, naming each node that ran in it once
+func (e *superstepEngine[S]) endStep(ctx context.Context, executed []task[S], state S, next []string, sends []Send) error {
+	nodes := make([]string, len(executed))
+	for i, t := range executed {
+		nodes[i] = t.node
+	}
+	nodes = e.schedule(nodes)
+
+	for _, hook := range e.hooks {
+		if err := hook.endStep(ctx, nodes, state, next, sends); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// traverse reports an edge traversal to the hooks
+func (e *superstepEngine[S]) traverse(ctx context.Context, from, to string, state S) {
+	for _, hook := range e.hooks {
+		hook.traverse(ctx, from, to, state)
+	}
+}
+
+// schedule removes END and duplicate nodes, keeping the order of first appearance.
+// A node reached through several edges therefore runs once per step (fan-in).
+func (e *superstepEngine[S]) schedule(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	scheduled := make([]string, 0, len(names))
+
+	for _, name := range names {
+		if name == END || seen[name] {
+			continue
+		}
+		seen[name] = true
+		scheduled = append(scheduled, name)
+	}
+
+	return scheduled
+}
@@ -0,0 +1,221 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+// appendMerger merges fan-out results by concatenating the items each node appended
+func appendMerger(_ context.Context, current []string, updates [][]string) ([]string, error) {
+	merged := append([]string{}, current...)
+	for _, update := range updates {
+		merged = append(merged, update[len(current):]...)
+	}
+	return merged, nil
+}
+
+func appendNode(name string) func(context.Context, []string) ([]string, error) {
+	return func(_ context.Context, state []string) ([]string, error) {
+		return append(append([]string{}, state...), name), nil
+	}
+}
+
+func TestSuperstep_FanOutFanIn(t *testing.T) {
+	t.Parallel()
+
+	// Both branches must be running at the same time to get past the barrier
+	var barrier sync.WaitGroup
+	barrier.Add(2)
+	branch := func(name string) func(context.Context, []string) ([]string, error) {
+		return func(ctx context.Context, state []string) ([]string, error) {
+			barrier.Done()
+			done := make(chan struct{})
+			go func() {
+				barrier.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				return nil, errors.New("branches did not run concurrently")
+			}
+			return append(append([]string{}, state...), name), nil
+		}
+	}
+
+	var joinRuns int32
+	g := graph.NewTypedMessageGraph[[]string]()
+	g.AddNode("split", appendNode("split"))
+	g.AddNode("left", branch("left"))
+	g.AddNode("right", branch("right"))
+	g.AddNode("join", func(ctx context.Context, state []string) ([]string, error) {
+		atomic.AddInt32(&joinRuns, 1)
+		return appendNode("join")(ctx, state)
+	})
+	g.AddEdge("split", "left")
+	g.AddEdge("split", "right")
+	g.AddEdge("left", "join")
+	g.AddEdge("right", "join")
+	g.AddEdge("join", graph.END)
+	g.SetEntryPoint("split")
+	g.SetStateMerger(appendMerger)
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected invoke error: %v", err)
+	}
+
+	expected := "split,left,right,join"
+	if got := strings.Join(result, ","); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	if joinRuns != 1 {
+		t.Errorf("expected join to run once, ran %d times", joinRuns)
+	}
+}
+
+func TestSuperstep_ConcurrentUpdateWithoutMerger(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewMessageGraph()
+	g.AddNode("start", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	g.AddNode("a", func(_ context.Context, state interface{}) (interface{}, error) {
+		return "a", nil
+	})
+	g.AddNode("b", func(_ context.Context, state interface{}) (interface{}, error) {
+		return "b", nil
+	})
+	g.AddEdge("start", "a")
+	g.AddEdge("start", "b")
+	g.AddEdge("a", graph.END)
+	g.AddEdge("b", graph.END)
+	g.SetEntryPoint("start")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	_, err = runnable.Invoke(context.Background(), "input")
+	if !errors.Is(err, graph.ErrInvalidConcurrentUpdate) {
+		t.Errorf("expected ErrInvalidConcurrentUpdate, got %v", err)
+	}
+}
+
+func TestSuperstep_StateGraphBranchesSeeSameInput(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewStateGraph()
+	g.AddNode("start", func(_ context.Context, state interface{}) (interface{}, error) {
+		return map[string]interface{}{"visited": []string{}}, nil
+	})
+	for _, name := range []string{"x", "y", "z"} {
+		name := name
+		g.AddNode(name, func(_ context.Context, state interface{}) (interface{}, error) {
+			visited := state.(map[string]interface{})["visited"].([]string)
+			if len(visited) != 0 {
+				return nil, errors.New("branch observed another branch's update")
+			}
+			return map[string]interface{}{"visited": []string{name}}, nil
+		})
+		g.AddEdge("start", name)
+		g.AddEdge(name, graph.END)
+	}
+	g.SetEntryPoint("start")
+	g.SetStateMerger(func(_ context.Context, _ interface{}, updates []interface{}) (interface{}, error) {
+		var visited []string
+		for _, update := range updates {
+			visited = append(visited, update.(map[string]interface{})["visited"].([]string)...)
+		}
+		return map[string]interface{}{"visited": visited}, nil
+	})
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	result, err := runnable.Invoke(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected invoke error: %v", err)
+	}
+
+	visited := result.(map[string]interface{})["visited"].([]string)
+	sort.Strings(visited)
+	if strings.Join(visited, ",") != "x,y,z" {
+		t.Errorf("expected all branches to be merged, got %v", visited)
+	}
+}
+
+func TestSuperstep_PanicInParallelNodePropagates(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewTypedMessageGraph[[]string]()
+	g.AddNode("start", appendNode("start"))
+	g.AddNode("ok", appendNode("ok"))
+	g.AddNode("boom", func(context.Context, []string) ([]string, error) {
+		panic("intentional panic")
+	})
+	g.AddEdge("start", "ok")
+	g.AddEdge("start", "boom")
+	g.AddEdge("ok", graph.END)
+	g.AddEdge("boom", graph.END)
+	g.SetEntryPoint("start")
+	g.SetStateMerger(appendMerger)
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic to propagate to the caller")
+		}
+	}()
+
+	_, _ = runnable.Invoke(context.Background(), nil)
+}
+
+func TestRunnable_ResumeFrom(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewTypedMessageGraph[[]string]()
+	g.AddNode("first", appendNode("first"))
+	g.AddNode("second", appendNode("second"))
+	g.AddEdge("first", "second")
+	g.AddEdge("second", graph.END)
+	g.SetEntryPoint("first")
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	result, err := runnable.InvokeWithConfig(context.Background(), []string{"saved"}, &graph.Config{
+		ResumeFrom: []string{"second"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(result) != "[saved second]" {
+		t.Errorf("expected run to start at second, got %v", result)
+	}
+}
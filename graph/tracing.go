@@ -2,7 +2,10 @@ package graph
 
 import (
 	"context"
+	"sync"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // TraceEvent represents different types of events in graph execution
@@ -85,6 +88,7 @@ func (f TraceHookFunc) OnEvent(ctx context.Context, span *TraceSpan) {
 type Tracer struct {
 	hooks []TraceHook
 	spans map[string]*TraceSpan
+	mutex sync.RWMutex
 }
 
 // NewTracer creates a new tracer instance
@@ -97,6 +101,9 @@ func NewTracer() *Tracer {
 
 // AddHook registers a new trace hook
 func (t *Tracer) AddHook(hook TraceHook) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
 	t.hooks = append(t.hooks, hook)
 }
 
@@ -115,10 +122,12 @@ func (t *Tracer) StartSpan(ctx context.Context, event TraceEvent, nodeName strin
 		span.ParentID = parentSpan.ID
 	}
 
+	t.mutex.Lock()
 	t.spans[span.ID] = span
+	t.mutex.Unlock()
 
 	// Notify hooks
-	for _, hook := range t.hooks {
+	for _, hook := range t.getHooks() {
 		hook.OnEvent(ctx, span)
 	}
 
@@ -142,7 +151,7 @@ func (t *Tracer) EndSpan(ctx context.Context, span *TraceSpan, state interface{}
 	}
 
 	// Notify hooks
-	for _, hook := range t.hooks {
+	for _, hook := range t.getHooks() {
 		hook.OnEvent(ctx, span)
 	}
 }
@@ -165,24 +174,46 @@ func (t *Tracer) TraceEdgeTraversal(ctx context.Context, fromNode, toNode string
 		span.ParentID = parentSpan.ID
 	}
 
+	t.mutex.Lock()
 	t.spans[span.ID] = span
+	t.mutex.Unlock()
 
 	// Notify hooks
-	for _, hook := range t.hooks {
+	for _, hook := range t.getHooks() {
 		hook.OnEvent(ctx, span)
 	}
 }
 
 // GetSpans returns all collected spans
 func (t *Tracer) GetSpans() map[string]*TraceSpan {
-	return t.spans
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	spans := make(map[string]*TraceSpan, len(t.spans))
+	for id, span := range t.spans {
+		spans[id] = span
+	}
+	return spans
 }
 
 // Clear removes all collected spans
 func (t *Tracer) Clear() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
 	t.spans = make(map[string]*TraceSpan)
 }
 
+// getHooks returns a snapshot of the registered hooks
+func (t *Tracer) getHooks() []TraceHook {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	hooks := make([]TraceHook, len(t.hooks))
+	copy(hooks, t.hooks)
+	return hooks
+}
+
 // Context keys for span storage
 type contextKey string
 
@@ -203,7 +234,7 @@ func SpanFromContext(ctx context.Context) *TraceSpan {
 
 // generateSpanID creates a unique span identifier
 func generateSpanID() string {
-	return time.Now().Format("20060102150405.000000")
+	return uuid.New().String()
 }
 
 // TracedRunnable wraps a Runnable with tracing capabilities
@@ -212,74 +243,15 @@ type TracedRunnable struct {
 	tracer *Tracer
 }
 
-// NewTracedRunnable creates a new traced runnable
+// NewTracedRunnable creates a new traced runnable. Its runs record a span for the graph,
+// nested spans for its nodes and one for each edge traversal.
 func NewTracedRunnable(runnable *Runnable, tracer *Tracer) *TracedRunnable {
 	return &TracedRunnable{
-		Runnable: runnable,
+		Runnable: runnable.WithTracer(tracer),
 		tracer:   tracer,
 	}
 }
 
-// Invoke executes the graph with tracing enabled
-func (tr *TracedRunnable) Invoke(ctx context.Context, initialState interface{}) (interface{}, error) {
-	// Start graph execution span
-	graphSpan := tr.tracer.StartSpan(ctx, TraceEventGraphStart, "")
-	ctx = ContextWithSpan(ctx, graphSpan)
-
-	state := initialState
-	currentNode := tr.graph.entryPoint
-	var finalError error
-
-	for {
-		if currentNode == END {
-			break
-		}
-
-		node, ok := tr.graph.nodes[currentNode]
-		if !ok {
-			finalError = ErrNodeNotFound
-			tr.tracer.EndSpan(ctx, graphSpan, state, finalError)
-			return nil, finalError
-		}
-
-		// Start node execution span
-		nodeSpan := tr.tracer.StartSpan(ctx, TraceEventNodeStart, currentNode)
-		nodeCtx := ContextWithSpan(ctx, nodeSpan)
-
-		var err error
-		state, err = node.Function(nodeCtx, state)
-
-		// End node execution span
-		tr.tracer.EndSpan(nodeCtx, nodeSpan, state, err)
-
-		if err != nil {
-			finalError = err
-			tr.tracer.EndSpan(ctx, graphSpan, state, finalError)
-			return nil, finalError
-		}
-
-		// Find next node
-		foundNext := false
-		for _, edge := range tr.graph.edges {
-			if edge.From == currentNode {
-				tr.tracer.TraceEdgeTraversal(ctx, currentNode, edge.To)
-				currentNode = edge.To
-				foundNext = true
-				break
-			}
-		}
-
-		if !foundNext {
-			finalError = ErrNoOutgoingEdge
-			tr.tracer.EndSpan(ctx, graphSpan, state, finalError)
-			return nil, finalError
-		}
-	}
-
-	tr.tracer.EndSpan(ctx, graphSpan, state, nil)
-	return state, nil
-}
-
 // GetTracer returns the tracer instance
 func (tr *TracedRunnable) GetTracer() *Tracer {
 	return tr.tracer
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/paulnegz/langgraphgo/graph"
@@ -323,6 +324,61 @@ func TestTracer_SpanHierarchy(t *testing.T) {
 	}
 }
 
+func TestTracedRunnable_FanOutSpans(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewMessageGraph()
+	g.AddNode("start", func(_ context.Context, state interface{}) (interface{}, error) {
+		return state, nil
+	})
+	for i := 0; i < 8; i++ {
+		name := fmt.Sprintf("worker_%d", i)
+		g.AddNode(name, func(_ context.Context, state interface{}) (interface{}, error) {
+			return state, nil
+		})
+		g.AddEdge("start", name)
+		g.AddEdge(name, graph.END)
+	}
+	g.SetEntryPoint("start")
+	g.SetStateMerger(func(_ context.Context, current interface{}, _ []interface{}) (interface{}, error) {
+		return current, nil
+	})
+
+	runnable, err := g.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile graph: %v", err)
+	}
+
+	var mutex sync.Mutex
+	started := make(map[*graph.TraceSpan]bool)
+	tracer := graph.NewTracer()
+	tracer.AddHook(graph.TraceHookFunc(func(_ context.Context, span *graph.TraceSpan) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		started[span] = true
+	}))
+
+	if _, err := graph.NewTracedRunnable(runnable, tracer).Invoke(context.Background(), "test"); err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+
+	// Spans started in the same instant are all kept
+	spans := tracer.GetSpans()
+	if len(spans) != len(started) {
+		t.Errorf("Expected %d spans, got %d", len(started), len(spans))
+	}
+
+	nodes := 0
+	for _, span := range spans {
+		if span.NodeName != "" && span.Event != graph.TraceEventEdgeTraversal {
+			nodes++
+		}
+	}
+	if nodes != 9 {
+		t.Errorf("Expected 9 node spans, got %d", nodes)
+	}
+}
+
 // Benchmark tests
 func BenchmarkTracer_StartEndSpan(b *testing.B) {
 	tracer := graph.NewTracer()
@@ -0,0 +1,366 @@
+package graph
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrDuplicateEdge is returned when a node has the same edge twice.
+	ErrDuplicateEdge = errors.New("duplicate edge")
+
+	// ErrIgnoredEdge is reported for the static edges of a node that also has a conditional
+	// edge or a send edge, and for the send edge of a node that also has a conditional edge.
+	// Only the edge that takes precedence is followed.
+	ErrIgnoredEdge = errors.New("ignored edge")
+
+	// ErrUnreachableNode is reported for nodes that no edge leads to from the entry point.
+	ErrUnreachableNode = errors.New("node is not reachable from the entry point")
+
+	// ErrNoPathToEnd is reported for cycles whose nodes have no edge leading out of the cycle.
+	ErrNoPathToEnd = errors.New("cycle never reaches END")
+)
+
+// ValidationProblem is a problem found in the structure of a graph
+type ValidationProblem struct {
+	// Err is the kind of problem, such as ErrNodeNotFound or ErrUnreachableNode
+	Err error
+
+	// Nodes are the names of the nodes involved
+	Nodes []string
+
+	// Detail locates the problem, e.g. the edge that refers to a missing node
+	Detail string
+
+	// Warning is set for problems that don't prevent the graph from running, such as nodes
+	// that can still route with a Command. Compile only fails for problems that aren't warnings.
+	Warning bool
+}
+
+func (p ValidationProblem) Error() string {
+	message := p.Err.Error()
+	if len(p.Nodes) > 0 {
+		message += ": " + strings.Join(p.Nodes, ", ")
+	}
+	if p.Detail != "" {
+		message += " (" + p.Detail + ")"
+	}
+	return message
+}
+
+func (p ValidationProblem) Unwrap() error {
+	return p.Err
+}
+
+// ValidationError lists every problem found in the structure of a graph.
+// errors.Is matches it against the Err of each problem.
+type ValidationError struct {
+	Problems []ValidationProblem
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		messages[i] = problem.Error()
+		if problem.Warning {
+			messages[i] = "warning: " + messages[i]
+		}
+	}
+	return "invalid graph: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Problems))
+	for i, problem := range e.Problems {
+		errs[i] = problem
+	}
+	return errs
+}
+
+// topology is the structure of a graph, independent of the type of its state
+type topology struct {
+	entryPoint string
+	nodes      map[string]bool
+	edges      []Edge
+
+	// conditional and sends are the nodes with a conditional edge and a send edge
+	conditional map[string]bool
+	sends       map[string]bool
+
+	pathMaps map[string]map[string]string
+
+	// static lists the targets of the static edges of each node
+	static map[string][]string
+}
+
+func newTopology[S any, C, E any](entryPoint string, nodes map[string]TypedNode[S], edges []Edge, conditionalEdges map[string]C, sendEdges map[string]E, pathMaps map[string]map[string]string) topology {
+	t := topology{
+		entryPoint:  entryPoint,
+		nodes:       make(map[string]bool, len(nodes)),
+		edges:       edges,
+		conditional: make(map[string]bool, len(conditionalEdges)),
+		sends:       make(map[string]bool, len(sendEdges)),
+		pathMaps:    pathMaps,
+	}
+	for name := range nodes {
+		t.nodes[name] = true
+	}
+	for name := range conditionalEdges {
+		t.conditional[name] = true
+	}
+	for name := range sendEdges {
+		t.sends[name] = true
+	}
+	t.static = make(map[string][]string)
+	for _, edge := range edges {
+		t.static[edge.From] = append(t.static[edge.From], edge.To)
+	}
+	return t
+}
+
+// check returns a ValidationError listing the problems that prevent the graph from compiling
+func (t topology) check() error {
+	var problems []ValidationProblem
+	for _, problem := range t.validate() {
+		if !problem.Warning {
+			problems = append(problems, problem)
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
+
+// validate returns every problem found in the graph, warnings included
+func (t topology) validate() []ValidationProblem {
+	var problems []ValidationProblem
+
+	if t.entryPoint == "" {
+		problems = append(problems, ValidationProblem{Err: ErrEntryPointNotSet})
+	} else if !t.nodes[t.entryPoint] {
+		problems = append(problems, ValidationProblem{Err: ErrNodeNotFound, Nodes: []string{t.entryPoint}, Detail: "entry point"})
+	}
+
+	problems = append(problems, t.validateEdges()...)
+
+	for _, name := range sortedKeys(t.nodes) {
+		if name != END && len(t.successors(name)) == 0 && !t.conditional[name] && !t.sends[name] {
+			problems = append(problems, ValidationProblem{Err: ErrNoOutgoingEdge, Nodes: []string{name}, Warning: true})
+		}
+	}
+
+	if t.nodes[t.entryPoint] {
+		if unreachable := t.unreachable(); len(unreachable) > 0 {
+			problems = append(problems, ValidationProblem{Err: ErrUnreachableNode, Nodes: unreachable, Warning: true})
+		}
+	}
+
+	for _, cycle := range t.closedCycles() {
+		problems = append(problems, ValidationProblem{Err: ErrNoPathToEnd, Nodes: cycle, Warning: true})
+	}
+
+	return problems
+}
+
+// validateEdges checks that every edge connects existing nodes, and that no edge is ignored
+func (t topology) validateEdges() []ValidationProblem {
+	var problems []ValidationProblem
+
+	seen := make(map[Edge]bool, len(t.edges))
+	for _, edge := range t.edges {
+		if !t.nodes[edge.From] {
+			problems = append(problems, ValidationProblem{Err: ErrNodeNotFound, Nodes: []string{edge.From}, Detail: "edge to " + edge.To})
+		}
+		if edge.To != END && !t.nodes[edge.To] {
+			problems = append(problems, ValidationProblem{Err: ErrNodeNotFound, Nodes: []string{edge.To}, Detail: "edge from " + edge.From})
+		}
+		if seen[edge] {
+			problems = append(problems, ValidationProblem{Err: ErrDuplicateEdge, Nodes: []string{edge.From, edge.To}})
+		}
+		seen[edge] = true
+	}
+
+	for _, name := range sortedKeys(t.conditional) {
+		if !t.nodes[name] {
+			problems = append(problems, ValidationProblem{Err: ErrNodeNotFound, Nodes: []string{name}, Detail: "conditional edge"})
+		}
+		for _, key := range sortedKeys(t.pathMaps[name]) {
+			if target := t.pathMaps[name][key]; target != END && !t.nodes[target] {
+				problems = append(problems, ValidationProblem{Err: ErrNodeNotFound, Nodes: []string{target}, Detail: "conditional edge from " + name})
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(t.sends) {
+		if !t.nodes[name] {
+			problems = append(problems, ValidationProblem{Err: ErrNodeNotFound, Nodes: []string{name}, Detail: "send edge"})
+		}
+	}
+
+	// Conditional edges take precedence over send edges, which take precedence over static edges
+	for _, name := range sortedKeys(t.nodes) {
+		var ignored []string
+		if t.conditional[name] && t.sends[name] {
+			ignored = append(ignored, "send edge")
+		}
+		if (t.conditional[name] || t.sends[name]) && len(t.static[name]) > 0 {
+			ignored = append(ignored, "static edges")
+		}
+		if len(ignored) > 0 {
+			problems = append(problems, ValidationProblem{Err: ErrIgnoredEdge, Nodes: []string{name}, Detail: "the " + strings.Join(ignored, " and ") + " of the node are ignored", Warning: true})
+		}
+	}
+
+	return problems
+}
+
+// successors returns the nodes known to follow a node, in the order of its edges
+func (t topology) successors(name string) []string {
+	if t.conditional[name] {
+		var targets []string
+		for _, key := range sortedKeys(t.pathMaps[name]) {
+			targets = append(targets, t.pathMaps[name][key])
+		}
+		return targets
+	}
+	if t.sends[name] {
+		return nil
+	}
+	return t.static[name]
+}
+
+// opaque reports whether the targets of a node's edges are only known at run time
+func (t topology) opaque(name string) bool {
+	if t.conditional[name] {
+		_, ok := t.pathMaps[name]
+		return !ok
+	}
+	return t.sends[name]
+}
+
+// unreachable returns the nodes that can't be reached from the entry point. It returns
+// nothing when a reachable node has an edge whose targets are only known at run time.
+func (t topology) unreachable() []string {
+	reached := map[string]bool{t.entryPoint: true}
+	queue := []string{t.entryPoint}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if t.opaque(name) {
+			return nil
+		}
+		for _, target := range t.successors(name) {
+			if !reached[target] {
+				reached[target] = true
+				queue = append(queue, target)
+			}
+		}
+	}
+
+	var unreachable []string
+	for _, name := range sortedKeys(t.nodes) {
+		if name != END && !reached[name] {
+			unreachable = append(unreachable, name)
+		}
+	}
+	return unreachable
+}
+
+// closedCycles returns the cycles of the graph from which END can't be reached, each
+// sorted by node name
+func (t topology) closedCycles() [][]string {
+	// A node reaches END through an edge to it, an edge whose targets are only known
+	// at run time, or a node that does
+	predecessors := make(map[string][]string)
+	var queue []string
+	for _, name := range sortedKeys(t.nodes) {
+		if t.opaque(name) {
+			queue = append(queue, name)
+		}
+		for _, target := range t.successors(name) {
+			predecessors[target] = append(predecessors[target], name)
+		}
+	}
+	queue = append(queue, END)
+	reachesEnd := make(map[string]bool)
+	for _, name := range queue {
+		reachesEnd[name] = true
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, predecessor := range predecessors[name] {
+			if !reachesEnd[predecessor] {
+				reachesEnd[predecessor] = true
+				queue = append(queue, predecessor)
+			}
+		}
+	}
+
+	var cycles [][]string
+	for _, component := range t.components() {
+		if reachesEnd[component[0]] {
+			continue
+		}
+		if len(component) == 1 && !slices.Contains(t.successors(component[0]), component[0]) {
+			continue
+		}
+		sort.Strings(component)
+		cycles = append(cycles, component)
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
+
+// components returns the strongly connected components of the graph (Tarjan's algorithm)
+func (t topology) components() [][]string {
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+
+	var visit func(name string)
+	visit = func(name string) {
+		index[name] = len(index)
+		lowlink[name] = index[name]
+		stack = append(stack, name)
+		onStack[name] = true
+
+		for _, target := range t.successors(name) {
+			if !t.nodes[target] {
+				continue
+			}
+			if _, ok := index[target]; !ok {
+				visit(target)
+				lowlink[name] = min(lowlink[name], lowlink[target])
+			} else if onStack[target] {
+				lowlink[name] = min(lowlink[name], index[target])
+			}
+		}
+
+		if lowlink[name] == index[name] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == name {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	for _, name := range sortedKeys(t.nodes) {
+		if _, ok := index[name]; !ok {
+			visit(name)
+		}
+	}
+	return components
+}
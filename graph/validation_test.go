@@ -0,0 +1,152 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/paulnegz/langgraphgo/graph"
+)
+
+func passThrough(_ context.Context, state interface{}) (interface{}, error) {
+	return state, nil
+}
+
+func TestMessageGraph_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		buildGraph func(g *graph.MessageGraph)
+		expected   []graph.ValidationProblem
+	}{
+		{
+			name: "valid graph",
+			buildGraph: func(g *graph.MessageGraph) {
+				g.AddEdge("a", "b")
+				g.AddEdge("b", graph.END)
+				g.SetEntryPoint("a")
+			},
+		},
+		{
+			name: "unknown entry point and edge targets",
+			buildGraph: func(g *graph.MessageGraph) {
+				g.AddEdge("a", "missing")
+				g.AddEdge("ghost", "b")
+				g.AddEdge("b", graph.END)
+				g.SetEntryPoint("nowhere")
+			},
+			expected: []graph.ValidationProblem{
+				{Err: graph.ErrNodeNotFound, Nodes: []string{"nowhere"}, Detail: "entry point"},
+				{Err: graph.ErrNodeNotFound, Nodes: []string{"missing"}, Detail: "edge from a"},
+				{Err: graph.ErrNodeNotFound, Nodes: []string{"ghost"}, Detail: "edge to b"},
+			},
+		},
+		{
+			name: "duplicate and ignored edges",
+			buildGraph: func(g *graph.MessageGraph) {
+				g.AddEdge("a", "b")
+				g.AddEdge("a", "b")
+				g.AddEdge("b", graph.END)
+				g.AddConditionalEdgeWithTargets("b", func(context.Context, interface{}) string { return graph.END }, graph.END)
+				g.SetEntryPoint("a")
+			},
+			expected: []graph.ValidationProblem{
+				{Err: graph.ErrDuplicateEdge, Nodes: []string{"a", "b"}},
+				{Err: graph.ErrIgnoredEdge, Nodes: []string{"b"}, Detail: "the static edges of the node are ignored", Warning: true},
+			},
+		},
+		{
+			name: "dead end and unreachable node",
+			buildGraph: func(g *graph.MessageGraph) {
+				g.AddEdge("b", graph.END)
+				g.SetEntryPoint("a")
+			},
+			expected: []graph.ValidationProblem{
+				{Err: graph.ErrNoOutgoingEdge, Nodes: []string{"a"}, Warning: true},
+				{Err: graph.ErrUnreachableNode, Nodes: []string{"b"}, Warning: true},
+			},
+		},
+		{
+			name: "cycle without exit",
+			buildGraph: func(g *graph.MessageGraph) {
+				g.AddEdge("a", "b")
+				g.AddEdge("b", "a")
+				g.SetEntryPoint("a")
+			},
+			expected: []graph.ValidationProblem{
+				{Err: graph.ErrNoPathToEnd, Nodes: []string{"a", "b"}, Warning: true},
+			},
+		},
+		{
+			name: "cycle with a conditional exit",
+			buildGraph: func(g *graph.MessageGraph) {
+				g.AddEdge("a", "b")
+				g.AddConditionalEdge("b", func(context.Context, interface{}) string { return graph.END })
+				g.SetEntryPoint("a")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			g := graph.NewMessageGraph()
+			g.AddNode("a", passThrough)
+			g.AddNode("b", passThrough)
+			tt.buildGraph(g)
+
+			err := g.Validate()
+			if tt.expected == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var validationErr *graph.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+			if !reflect.DeepEqual(validationErr.Problems, tt.expected) {
+				t.Errorf("expected problems %v, got %v", tt.expected, validationErr.Problems)
+			}
+		})
+	}
+}
+
+func TestMessageGraph_CompileValidation(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewMessageGraph()
+	g.AddNode("a", passThrough)
+	g.AddNode("b", passThrough)
+	g.AddEdge("a", "missing")
+	g.AddEdge("a", "b")
+	g.AddEdge("a", "b")
+
+	// Every problem is reported at once
+	_, err := g.Compile()
+	if !errors.Is(err, graph.ErrEntryPointNotSet) || !errors.Is(err, graph.ErrNodeNotFound) || !errors.Is(err, graph.ErrDuplicateEdge) {
+		t.Errorf("expected the entry point, missing node and duplicate edge to be reported, got %v", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "missing") {
+		t.Errorf("expected the error to name the missing node, got %v", err)
+	}
+
+	// Warnings don't prevent compiling, since b could route with a Command
+	warned := graph.NewListenableMessageGraph()
+	warned.AddNode("a", passThrough)
+	warned.AddNode("b", passThrough)
+	warned.AddEdge("a", graph.END)
+	warned.SetEntryPoint("a")
+	if _, err := warned.CompileListenable(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := warned.Validate(); !errors.Is(err, graph.ErrUnreachableNode) || !errors.Is(err, graph.ErrNoOutgoingEdge) {
+		t.Errorf("expected warnings for b, got %v", err)
+	}
+}
@@ -2,18 +2,19 @@ package graph
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 )
 
 // Exporter provides methods to export graphs in different formats
 type Exporter struct {
-	graph *MessageGraph
+	graph *TypedMessageGraph[interface{}]
 }
 
 // NewExporter creates a new graph exporter for the given graph
 func NewExporter(graph *MessageGraph) *Exporter {
-	return &Exporter{graph: graph}
+	return &Exporter{graph: graph.TypedMessageGraph}
 }
 
 // DrawMermaid generates a Mermaid diagram representation of the graph
@@ -46,15 +47,7 @@ func (ge *Exporter) DrawMermaid() string {
 	}
 
 	// Add END node if referenced
-	hasEnd := false
-	for _, edge := range ge.graph.edges {
-		if edge.To == END {
-			hasEnd = true
-			break
-		}
-	}
-
-	if hasEnd {
+	if ge.hasEnd() {
 		sb.WriteString("    END([\"END\"])\n")
 		sb.WriteString("    style END fill:#FFB6C1\n")
 	}
@@ -64,6 +57,17 @@ func (ge *Exporter) DrawMermaid() string {
 		sb.WriteString(fmt.Sprintf("    %s --> %s\n", edge.From, edge.To))
 	}
 
+	// Add the branches of conditional edges as dotted edges
+	for _, from := range sortedKeys(ge.graph.pathMaps) {
+		for _, b := range branches(ge.graph.pathMaps[from]) {
+			if b.label == "" {
+				sb.WriteString(fmt.Sprintf("    %s -.-> %s\n", from, b.to))
+			} else {
+				sb.WriteString(fmt.Sprintf("    %s -.->|%s| %s\n", from, b.label, b.to))
+			}
+		}
+	}
+
 	// Style entry point
 	if ge.graph.entryPoint != "" {
 		sb.WriteString(fmt.Sprintf("    style %s fill:#87CEEB\n", ge.graph.entryPoint))
@@ -92,15 +96,7 @@ func (ge *Exporter) DrawDOT() string {
 	}
 
 	// Add END node styling if referenced
-	hasEnd := false
-	for _, edge := range ge.graph.edges {
-		if edge.To == END {
-			hasEnd = true
-			break
-		}
-	}
-
-	if hasEnd {
+	if ge.hasEnd() {
 		sb.WriteString("    END [label=\"END\", shape=ellipse, style=filled, fillcolor=lightpink];\n")
 	}
 
@@ -109,6 +105,17 @@ func (ge *Exporter) DrawDOT() string {
 		sb.WriteString(fmt.Sprintf("    %s -> %s;\n", edge.From, edge.To))
 	}
 
+	// Add the branches of conditional edges as dashed edges
+	for _, from := range sortedKeys(ge.graph.pathMaps) {
+		for _, b := range branches(ge.graph.pathMaps[from]) {
+			if b.label == "" {
+				sb.WriteString(fmt.Sprintf("    %s -> %s [style=dashed];\n", from, b.to))
+			} else {
+				sb.WriteString(fmt.Sprintf("    %s -> %s [label=\"%s\", style=dashed];\n", from, b.to, b.label))
+			}
+		}
+	}
+
 	sb.WriteString("}\n")
 	return sb.String()
 }
@@ -157,13 +164,18 @@ func (ge *Exporter) drawASCIINode(nodeName string, prefix string, isLast bool, v
 		return
 	}
 
-	// Find outgoing edges
+	// Find outgoing edges, including the branches of a conditional edge
 	outgoingEdges := make([]string, 0)
 	for _, edge := range ge.graph.edges {
 		if edge.From == nodeName {
 			outgoingEdges = append(outgoingEdges, edge.To)
 		}
 	}
+	for _, b := range branches(ge.graph.pathMaps[nodeName]) {
+		if !slices.Contains(outgoingEdges, b.to) {
+			outgoingEdges = append(outgoingEdges, b.to)
+		}
+	}
 
 	// Sort for consistent output
 	sort.Strings(outgoingEdges)
@@ -175,7 +187,24 @@ func (ge *Exporter) drawASCIINode(nodeName string, prefix string, isLast bool, v
 	}
 }
 
+// hasEnd reports whether an edge or a branch of a conditional edge leads to END
+func (ge *Exporter) hasEnd() bool {
+	for _, edge := range ge.graph.edges {
+		if edge.To == END {
+			return true
+		}
+	}
+	for _, pathMap := range ge.graph.pathMaps {
+		for _, target := range pathMap {
+			if target == END {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // GetGraph returns a Exporter for the compiled graph's visualization
 func (r *Runnable) GetGraph() *Exporter {
-	return NewExporter(r.graph)
+	return &Exporter{graph: r.graph}
 }
@@ -353,6 +353,58 @@ func TestExporter_CycleDetection(t *testing.T) {
 	}
 }
 
+func TestExporter_ConditionalBranches(t *testing.T) {
+	t.Parallel()
+
+	g := graph.NewMessageGraph()
+	for _, name := range []string{"agent", "tools", "review"} {
+		g.AddNode(name, func(_ context.Context, state interface{}) (interface{}, error) {
+			return state, nil
+		})
+	}
+	g.AddConditionalEdgeWithPathMap("agent", func(_ context.Context, _ interface{}) string {
+		return "finish"
+	}, map[string]string{"call_tool": "tools", "finish": graph.END})
+	g.AddConditionalEdgeWithTargets("tools", func(_ context.Context, _ interface{}) string {
+		return "agent"
+	}, "agent", "review")
+	g.AddEdge("review", graph.END)
+	g.SetEntryPoint("agent")
+
+	exporter := graph.NewExporter(g)
+
+	mermaid := exporter.DrawMermaid()
+	for _, expectedLine := range []string{
+		`END(["END"])`,
+		"agent -.->|call_tool| tools",
+		"agent -.->|finish| END",
+		"tools -.-> agent",
+		"tools -.-> review",
+	} {
+		if !strings.Contains(mermaid, expectedLine) {
+			t.Errorf("Expected line not found in Mermaid output: %q\nActual output:\n%s", expectedLine, mermaid)
+		}
+	}
+
+	dot := exporter.DrawDOT()
+	for _, expectedLine := range []string{
+		`agent -> tools [label="call_tool", style=dashed];`,
+		`agent -> END [label="finish", style=dashed];`,
+		"tools -> review [style=dashed];",
+	} {
+		if !strings.Contains(dot, expectedLine) {
+			t.Errorf("Expected line not found in DOT output: %q\nActual output:\n%s", expectedLine, dot)
+		}
+	}
+
+	ascii := exporter.DrawASCII()
+	for _, name := range []string{"tools", "review", "END", "agent (cycle)"} {
+		if !strings.Contains(ascii, name) {
+			t.Errorf("ASCII output should include the branch to %s, got: %s", name, ascii)
+		}
+	}
+}
+
 // Benchmark tests
 func BenchmarkExporter_DrawMermaid(b *testing.B) {
 	g := graph.NewMessageGraph()